@@ -1,22 +1,15 @@
 package animation
 
 import (
-	"errors"
-	"image"
-	"image/color"
-	"image/draw"
-	"image/gif"
-	"os"
-
 	"github.com/aethiopicuschan/odori/constant"
-	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/soniakeys/quant/median"
 )
 
 type Animation struct {
 	Parts  []*Part `json:"parts"`
 	Width  int     `json:"width"`
 	Height int     `json:"height"`
+	// 前回GIFを出力した時の設定
+	GifOptions *GifOptions `json:"gifOptions,omitempty"`
 }
 
 func NewAnimation() *Animation {
@@ -31,48 +24,74 @@ func (a *Animation) CanExport() bool {
 	return len(a.Parts) > 0
 }
 
-func (a *Animation) ExportAsGif(path string) (err error) {
-	if !a.CanExport() {
-		return errors.New("can not export")
+// 各パーツの開始Tickと全体のTick数を返す
+func (a *Animation) Indexes() (indexes []int, maxTick int) {
+	indexes = []int{}
+	for _, part := range a.Parts {
+		indexes = append(indexes, maxTick)
+		maxTick += part.Length
 	}
-	outGif := &gif.GIF{}
+	return
+}
+
+// 各パーツの表示時間をミリ秒で返す
+func (a *Animation) Durations(tps int) (durations []int) {
+	lengths := []int{}
 	for _, part := range a.Parts {
-		frame := ebiten.NewImage(a.Width, a.Height)
-		scale := part.Scale
-		diffX := part.DiffX
-		diffY := part.DiffY
-		reverse := part.Reverse
-		if !part.Sprite.IsEmpty() {
-			img := part.Sprite.Image
-			op := &ebiten.DrawImageOptions{}
-			if reverse {
-				op.GeoM.Scale(-1, 1)
-				op.GeoM.Translate(float64(img.Bounds().Dx()), 0)
-				diffX *= -1
-			}
-			op.GeoM.Scale(scale, scale)
-			op.GeoM.Translate(-((float64(img.Bounds().Dx()-diffX))*scale)/2, -((float64(img.Bounds().Dy()-diffY))*scale)/2)
-			op.GeoM.Translate(float64(a.Width/2), float64(a.Height/2))
-			frame.DrawImage(img, op)
+		lengths = append(lengths, part.Length)
+	}
+	return ConvertDurations(lengths, tps, 1000, 0)
+}
+
+// 空のパーツの表示時間を直前の空でないパーツに足した表示時間を返す
+// 直前になければ次の空でないパーツに足し、空のパーツ自体は0になる
+func foldEmptyDurations(durations []int, isEmpty func(i int) bool) (folded []int) {
+	folded = make([]int, len(durations))
+	last := -1
+	// 最初の空でないパーツより前にある空のパーツの表示時間
+	pending := 0
+	for i, d := range durations {
+		if !isEmpty(i) {
+			folded[i] = d + pending
+			pending = 0
+			last = i
+		} else if last >= 0 {
+			folded[last] += d
+		} else {
+			pending += d
 		}
-		// パレットを作成
-		q := median.Quantizer(255)
-		p := color.Palette{image.Transparent}
-		p = append(p, q.Quantize(make(color.Palette, 0, 255), frame)...)
-		// frameをPalettedに変換してoutGifに追加
-		paletted := image.NewPaletted(image.Rect(0, 0, a.Width, a.Height), p)
-		draw.Draw(paletted, paletted.Rect, frame, image.Point{0, 0}, draw.Src)
-		outGif.Image = append(outGif.Image, paletted)
-		// GifのDelayは1/100なので、変換する
-		delay := float64(part.Length) / float64(ebiten.TPS()) * 100
-		outGif.Delay = append(outGif.Delay, int(delay))
-		outGif.Disposal = append(outGif.Disposal, gif.DisposalBackground)
 	}
-	file, err := os.Create(path)
-	if err != nil {
-		return
+	return
+}
+
+// 1秒あたりfrom単位の長さの列を、1秒あたりto単位の長さの列に変換する
+// 長さごとに丸めると誤差が蓄積するので、開始からの累積時間を四捨五入した差分にする
+// minimumより短くなる長さはminimumにし、延びた分は以降の長さで吸収する
+func ConvertDurations(lengths []int, from, to, minimum int) (converted []int) {
+	elapsed := 0
+	prev := 0
+	for _, length := range lengths {
+		elapsed += length
+		current := (elapsed*to*2 + from) / (from * 2)
+		d := max(current-prev, minimum)
+		converted = append(converted, d)
+		prev += d
 	}
-	defer file.Close()
-	err = gif.EncodeAll(file, outGif)
 	return
 }
+
+// tickの時点で表示されるパーツの添字を返す
+// パーツがない場合は-1
+func PartIndexAt(indexes []int, tick int) int {
+	index := -1
+	for i, start := range indexes {
+		if tick < start {
+			break
+		}
+		index = i
+	}
+	if index < 0 && len(indexes) > 0 {
+		index = 0
+	}
+	return index
+}
@@ -4,7 +4,12 @@ import "image"
 
 // 永続化用モデル
 type AnimationP struct {
-	Name        string                     `json:"name"`
-	Animation   *Animation                 `json:"animation"`
-	SpriteSheet map[string]image.Rectangle `json:"spriteSheet"`
+	// 古い形式のJSONにはないので0として扱う
+	FormatVersion int                        `json:"formatVersion"`
+	Name          string                     `json:"name"`
+	Animation     *Animation                 `json:"animation"`
+	SpriteSheet   map[string]image.Rectangle `json:"spriteSheet"`
+	// Explorerに並んでいたスプライト
+	// 古い形式のJSONにはないのでnilになる
+	Library []LibraryEntry `json:"library,omitempty"`
 }
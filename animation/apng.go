@@ -0,0 +1,209 @@
+package animation
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/draw"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// APNGのフレーム制御に使う値
+const (
+	apngDisposeOpBackground = 1
+	apngBlendOpSource       = 0
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// APNG出力時の設定
+type ApngOptions struct {
+	// GifOptionsと同じく、0で無限ループ、-1でループしない
+	LoopCount int
+}
+
+func DefaultApngOptions() ApngOptions {
+	return ApngOptions{
+		LoopCount: 0,
+	}
+}
+
+// ダイアログで編集するための文字列表現
+func (o ApngOptions) String() string {
+	return fmt.Sprintf("loop=%d", o.LoopCount)
+}
+
+// "loop=0"の形式を解釈する
+func ParseApngOptions(s string) (o ApngOptions, err error) {
+	o = DefaultApngOptions()
+	for _, field := range strings.Fields(s) {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			err = fmt.Errorf("invalid option: %s", field)
+			return
+		}
+		switch key {
+		case "loop":
+			o.LoopCount, err = strconv.Atoi(value)
+			if err == nil && o.LoopCount < -1 {
+				err = errors.New("loop must be -1 or greater")
+			}
+		default:
+			err = fmt.Errorf("unknown option: %s", key)
+		}
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// acTLのnum_playsは再生回数なので、GIFのループ回数から変換する
+func (o ApngOptions) numPlays() uint32 {
+	switch {
+	case o.LoopCount == 0:
+		return 0
+	case o.LoopCount < 0:
+		return 1
+	default:
+		return uint32(o.LoopCount) + 1
+	}
+}
+
+// 減色せずにRGBAのままAPNGとして書き出す
+func (a *Animation) ExportAsApng(path string, opts ApngOptions, tps int) (err error) {
+	if !a.CanExport() {
+		return errors.New("can not export")
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	err = a.encodeApng(file, opts, tps)
+	return
+}
+
+func (a *Animation) encodeApng(w io.Writer, opts ApngOptions, tps int) (err error) {
+	buf := &bytes.Buffer{}
+	buf.Write(pngSignature)
+
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:4], uint32(a.Width))
+	binary.BigEndian.PutUint32(ihdr[4:8], uint32(a.Height))
+	ihdr[8] = 8  // bit depth
+	ihdr[9] = 6  // truecolor with alpha
+	ihdr[10] = 0 // compression
+	ihdr[11] = 0 // filter
+	ihdr[12] = 0 // interlace
+	writePngChunk(buf, "IHDR", ihdr)
+
+	// フレーム数と再生回数(0は無限)
+	actl := make([]byte, 8)
+	binary.BigEndian.PutUint32(actl[0:4], uint32(len(a.Parts)))
+	binary.BigEndian.PutUint32(actl[4:8], opts.numPlays())
+	writePngChunk(buf, "acTL", actl)
+
+	// fcTLとfdATで共通の連番
+	sequence := uint32(0)
+	for i, part := range a.Parts {
+		delayNum, delayDen := apngDelay(part.Length, tps)
+		fctl := make([]byte, 26)
+		binary.BigEndian.PutUint32(fctl[0:4], sequence)
+		binary.BigEndian.PutUint32(fctl[4:8], uint32(a.Width))
+		binary.BigEndian.PutUint32(fctl[8:12], uint32(a.Height))
+		binary.BigEndian.PutUint32(fctl[12:16], 0)
+		binary.BigEndian.PutUint32(fctl[16:20], 0)
+		binary.BigEndian.PutUint16(fctl[20:22], delayNum)
+		binary.BigEndian.PutUint16(fctl[22:24], delayDen)
+		fctl[24] = apngDisposeOpBackground
+		fctl[25] = apngBlendOpSource
+		writePngChunk(buf, "fcTL", fctl)
+		sequence++
+
+		var data []byte
+		data, err = compressFrame(a.RenderFrame(part))
+		if err != nil {
+			return
+		}
+		// 最初のフレームはAPNG非対応のビューアでも表示できるようにIDATにする
+		if i == 0 {
+			writePngChunk(buf, "IDAT", data)
+		} else {
+			fdat := make([]byte, 4, 4+len(data))
+			binary.BigEndian.PutUint32(fdat, sequence)
+			fdat = append(fdat, data...)
+			writePngChunk(buf, "fdAT", fdat)
+			sequence++
+		}
+	}
+	writePngChunk(buf, "IEND", nil)
+	_, err = w.Write(buf.Bytes())
+	return
+}
+
+// Tick数をフレームの表示時間(delayNum/delayDen秒)に変換する
+func apngDelay(length, tps int) (delayNum, delayDen uint16) {
+	g := gcd(length, tps)
+	num, den := length/g, tps/g
+	// uint16に収まらない場合はミリ秒で近似する
+	if num > 0xffff || den > 0xffff {
+		ms := length * 1000 / tps
+		if ms > 0xffff {
+			ms = 0xffff
+		}
+		return uint16(ms), 1000
+	}
+	return uint16(num), uint16(den)
+}
+
+// フィルタなしでRGBAの画素をzlib圧縮する
+func compressFrame(frame *image.RGBA) (data []byte, err error) {
+	// PNGはストレートアルファなので変換する
+	nrgba := image.NewNRGBA(frame.Bounds())
+	draw.Draw(nrgba, nrgba.Bounds(), frame, frame.Bounds().Min, draw.Src)
+	buf := &bytes.Buffer{}
+	zw := zlib.NewWriter(buf)
+	width := nrgba.Bounds().Dx()
+	for y := 0; y < nrgba.Bounds().Dy(); y++ {
+		row := nrgba.Pix[y*nrgba.Stride : y*nrgba.Stride+width*4]
+		_, err = zw.Write(append([]byte{0}, row...))
+		if err != nil {
+			return
+		}
+	}
+	err = zw.Close()
+	if err != nil {
+		return
+	}
+	data = buf.Bytes()
+	return
+}
+
+func writePngChunk(w *bytes.Buffer, name string, data []byte) {
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header[0:4], uint32(len(data)))
+	copy(header[4:8], name)
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:8])
+	crc.Write(data)
+	w.Write(header)
+	w.Write(data)
+	footer := make([]byte, 4)
+	binary.BigEndian.PutUint32(footer, crc.Sum32())
+	w.Write(footer)
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
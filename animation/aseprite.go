@@ -0,0 +1,102 @@
+package animation
+
+import (
+	"fmt"
+	"image"
+)
+
+// Asepriteの`--format json-array`と同じ形式のJSON
+type AsepriteSheet struct {
+	Frames []AsepriteFrame `json:"frames"`
+	Meta   AsepriteMeta    `json:"meta"`
+}
+
+type AsepriteFrame struct {
+	Filename string       `json:"filename"`
+	Frame    AsepriteRect `json:"frame"`
+	Rotated  bool         `json:"rotated"`
+	Trimmed  bool         `json:"trimmed"`
+	// キャンバス内でのスプライトの位置
+	SpriteSourceSize AsepriteRect `json:"spriteSourceSize"`
+	SourceSize       AsepriteSize `json:"sourceSize"`
+	// 表示時間(ミリ秒)
+	Duration int `json:"duration"`
+}
+
+type AsepriteRect struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+type AsepriteSize struct {
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+type AsepriteMeta struct {
+	App       string             `json:"app"`
+	Version   string             `json:"version"`
+	Image     string             `json:"image"`
+	Format    string             `json:"format"`
+	Size      AsepriteSize       `json:"size"`
+	Scale     string             `json:"scale"`
+	FrameTags []AsepriteFrameTag `json:"frameTags"`
+	Layers    []any              `json:"layers"`
+	Slices    []any              `json:"slices"`
+}
+
+type AsepriteFrameTag struct {
+	Name      string `json:"name"`
+	From      int    `json:"from"`
+	To        int    `json:"to"`
+	Direction string `json:"direction"`
+}
+
+// スプライトシートの位置情報からAseprite形式のJSONを作る
+// Asepriteの形式では拡大縮小と反転を表せないため、そのようなパーツの番号をinexactPartsとして返す
+func NewAsepriteSheet(animationP AnimationP, rectsMap map[string]image.Rectangle, imageName string, imageSize image.Point, tps int) (sheet AsepriteSheet, inexactParts []int) {
+	a := animationP.Animation
+	sheet.Frames = []AsepriteFrame{}
+	durations := a.Durations(tps)
+	for i, part := range a.Parts {
+		frame := AsepriteFrame{
+			Filename:   fmt.Sprintf("%s %d.png", animationP.Name, i),
+			Trimmed:    true,
+			SourceSize: AsepriteSize{W: a.Width, H: a.Height},
+			Duration:   durations[i],
+		}
+		if rect, ok := rectsMap[part.Sprite.Id()]; ok && !part.Sprite.IsEmpty() {
+			frame.Frame = AsepriteRect{X: rect.Min.X, Y: rect.Min.Y, W: rect.Dx(), H: rect.Dy()}
+			placement := a.Placement(part)
+			frame.SpriteSourceSize = AsepriteRect{X: placement.Min.X, Y: placement.Min.Y, W: placement.Dx(), H: placement.Dy()}
+			if frame.SpriteSourceSize.X == 0 && frame.SpriteSourceSize.Y == 0 && rect.Dx() == a.Width && rect.Dy() == a.Height {
+				frame.Trimmed = false
+			}
+			if part.Scale != 1 || part.Reverse {
+				inexactParts = append(inexactParts, i)
+			}
+		}
+		sheet.Frames = append(sheet.Frames, frame)
+	}
+	sheet.Meta = AsepriteMeta{
+		App:     "https://github.com/aethiopicuschan/odori",
+		Version: "1.0",
+		Image:   imageName,
+		Format:  "RGBA8888",
+		Size:    AsepriteSize{W: imageSize.X, H: imageSize.Y},
+		Scale:   "1",
+		FrameTags: []AsepriteFrameTag{
+			{
+				Name:      animationP.Name,
+				From:      0,
+				To:        len(a.Parts) - 1,
+				Direction: "forward",
+			},
+		},
+		Layers: []any{},
+		Slices: []any{},
+	}
+	return
+}
@@ -0,0 +1,186 @@
+package animation
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type BakedOrder string
+
+const (
+	// 左から右に並べ、端まで来たら次の行に進む
+	BakedRowMajor BakedOrder = "row"
+	// 上から下に並べ、端まで来たら次の列に進む
+	BakedColumnMajor BakedOrder = "column"
+)
+
+// 合成済みフレームのスプライトシートを書き出す時の設定
+type BakedOptions struct {
+	// 1行に並べるフレーム数
+	// 0の場合は全てのフレームを1行に並べる
+	Columns int
+	// セルの周囲の余白(ピクセル)
+	Padding int
+	Order   BakedOrder
+}
+
+func DefaultBakedOptions() BakedOptions {
+	return BakedOptions{
+		Columns: 0,
+		Padding: 0,
+		Order:   BakedRowMajor,
+	}
+}
+
+// ダイアログで編集するための文字列表現
+func (o BakedOptions) String() string {
+	return fmt.Sprintf("columns=%d padding=%d order=%s", o.Columns, o.Padding, o.Order)
+}
+
+// "columns=8 padding=1 order=row"の形式を解釈する
+func ParseBakedOptions(s string) (o BakedOptions, err error) {
+	o = DefaultBakedOptions()
+	for _, field := range strings.Fields(s) {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			err = fmt.Errorf("invalid option: %s", field)
+			return
+		}
+		switch key {
+		case "columns":
+			o.Columns, err = strconv.Atoi(value)
+			if err == nil && o.Columns < 0 {
+				err = errors.New("columns must be 0 or greater")
+			}
+		case "padding":
+			o.Padding, err = strconv.Atoi(value)
+			if err == nil && o.Padding < 0 {
+				err = errors.New("padding must be 0 or greater")
+			}
+		case "order":
+			switch BakedOrder(value) {
+			case BakedRowMajor, BakedColumnMajor:
+				o.Order = BakedOrder(value)
+			default:
+				err = fmt.Errorf("order must be row or column: %s", value)
+			}
+		default:
+			err = fmt.Errorf("unknown option: %s", key)
+		}
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// 合成済みのスプライトシートと一緒に書き出すJSON
+type BakedSheet struct {
+	Name  string `json:"name"`
+	Image string `json:"image"`
+	// シート全体のサイズ
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	// 1セルのサイズ
+	CellWidth  int          `json:"cellWidth"`
+	CellHeight int          `json:"cellHeight"`
+	Columns    int          `json:"columns"`
+	Rows       int          `json:"rows"`
+	Padding    int          `json:"padding"`
+	Frames     []BakedFrame `json:"frames"`
+}
+
+type BakedFrame struct {
+	// 元になったパーツの番号
+	Part   int `json:"part"`
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	// 表示時間(ミリ秒)
+	Duration int `json:"duration"`
+}
+
+// 各パーツをアニメーションのサイズで描画し、等間隔のグリッドに並べたシートを作る
+func (a *Animation) BakeSheet(opts BakedOptions, tps int) (sheet *image.RGBA, frames []BakedFrame, columns, rows int, err error) {
+	if !a.CanExport() {
+		err = errors.New("can not export")
+		return
+	}
+	if opts.Columns < 0 || opts.Padding < 0 {
+		err = errors.New("columns and padding must be 0 or greater")
+		return
+	}
+	if opts.Order != BakedRowMajor && opts.Order != BakedColumnMajor {
+		err = fmt.Errorf("order must be row or column: %s", opts.Order)
+		return
+	}
+	count := len(a.Parts)
+	columns = opts.Columns
+	if columns == 0 || columns > count {
+		columns = count
+	}
+	rows = (count + columns - 1) / columns
+	// 列ごとに詰めるので、最後の列より右に空の列ができないように列数を合わせる
+	if opts.Order == BakedColumnMajor {
+		columns = (count + rows - 1) / rows
+	}
+	cellW := a.Width + opts.Padding
+	cellH := a.Height + opts.Padding
+	sheet = image.NewRGBA(image.Rect(0, 0, opts.Padding+columns*cellW, opts.Padding+rows*cellH))
+	durations := a.Durations(tps)
+	for i, part := range a.Parts {
+		col, row := i%columns, i/columns
+		if opts.Order == BakedColumnMajor {
+			col, row = i/rows, i%rows
+		}
+		rect := image.Rect(0, 0, a.Width, a.Height).Add(image.Pt(opts.Padding+col*cellW, opts.Padding+row*cellH))
+		draw.Draw(sheet, rect, a.RenderFrame(part), image.Point{}, draw.Src)
+		frames = append(frames, BakedFrame{
+			Part:     i,
+			X:        rect.Min.X,
+			Y:        rect.Min.Y,
+			Width:    a.Width,
+			Height:   a.Height,
+			Duration: durations[i],
+		})
+	}
+	return
+}
+
+// "<name>_baked.png"と"<name>_baked.json"を書き出す
+func (a *Animation) ExportAsBakedSheet(dir, name string, opts BakedOptions, tps int) (err error) {
+	sheet, frames, columns, rows, err := a.BakeSheet(opts, tps)
+	if err != nil {
+		return
+	}
+	imageName := name + "_baked.png"
+	err = writePng(filepath.Join(dir, imageName), sheet)
+	if err != nil {
+		return
+	}
+	baked := BakedSheet{
+		Name:       name,
+		Image:      imageName,
+		Width:      sheet.Bounds().Dx(),
+		Height:     sheet.Bounds().Dy(),
+		CellWidth:  a.Width,
+		CellHeight: a.Height,
+		Columns:    columns,
+		Rows:       rows,
+		Padding:    opts.Padding,
+		Frames:     frames,
+	}
+	bytes, err := MarshalJson(baked)
+	if err != nil {
+		return
+	}
+	err = os.WriteFile(filepath.Join(dir, name+"_baked.json"), bytes, 0644)
+	return
+}
@@ -0,0 +1,54 @@
+package animation
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBakeSheet(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      BakedOptions
+		columns   int
+		rows      int
+		positions [][2]int
+	}{
+		{"row", BakedOptions{Columns: 4, Order: BakedRowMajor}, 4, 2, [][2]int{{0, 0}, {1, 0}, {2, 0}, {3, 0}, {0, 1}}},
+		// 2行に収まるので3列目までしか使わない
+		{"column", BakedOptions{Columns: 4, Order: BakedColumnMajor}, 3, 2, [][2]int{{0, 0}, {0, 1}, {1, 0}, {1, 1}, {2, 0}}},
+		{"column_padding", BakedOptions{Columns: 2, Padding: 1, Order: BakedColumnMajor}, 2, 3, [][2]int{{0, 0}, {0, 1}, {0, 2}, {1, 0}, {1, 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewAnimation()
+			a.Width = 4
+			a.Height = 3
+			for i := 0; i < 5; i++ {
+				a.Parts = append(a.Parts, NewPart(testSprite(), 1))
+			}
+			sheet, frames, columns, rows, err := a.BakeSheet(tt.opts, 60)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if columns != tt.columns || rows != tt.rows {
+				t.Fatalf("columns, rows = %d, %d, want %d, %d", columns, rows, tt.columns, tt.rows)
+			}
+			cellW, cellH := a.Width+tt.opts.Padding, a.Height+tt.opts.Padding
+			if sheet.Bounds().Dx() != tt.opts.Padding+columns*cellW || sheet.Bounds().Dy() != tt.opts.Padding+rows*cellH {
+				t.Fatalf("sheet = %v", sheet.Bounds())
+			}
+			durations := []int{}
+			for i, frame := range frames {
+				want := [2]int{tt.opts.Padding + tt.positions[i][0]*cellW, tt.opts.Padding + tt.positions[i][1]*cellH}
+				if [2]int{frame.X, frame.Y} != want {
+					t.Errorf("frame %d = (%d, %d), want %v", i, frame.X, frame.Y, want)
+				}
+				durations = append(durations, frame.Duration)
+			}
+			// 1/60秒ずつを累積で丸める
+			if want := []int{17, 16, 17, 17, 16}; !slices.Equal(durations, want) {
+				t.Fatalf("durations = %v, want %v", durations, want)
+			}
+		})
+	}
+}
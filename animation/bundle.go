@@ -0,0 +1,79 @@
+package animation
+
+import (
+	"encoding/json"
+	"io/fs"
+
+	"github.com/aethiopicuschan/odori/sprite"
+)
+
+// .odoriはzipで、以下のファイルを含む
+const (
+	BundleExt = ".odori"
+	// AnimationPのJSON
+	BundleManifestName = "project.json"
+	BundleSheetName    = "sheet.png"
+	// 元のスプライトの情報
+	BundleSpritesName = "sprites.json"
+	// 最初のパーツを描画した画像
+	BundleThumbnailName = "thumbnail.png"
+)
+
+// スプライトの元の情報
+type BundleSprite struct {
+	Id     string `json:"id"`
+	Name   string `json:"name,omitempty"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+func NewBundleSprites(sprites []sprite.Sprite) (bundleSprites []BundleSprite) {
+	bundleSprites = []BundleSprite{}
+	for _, s := range sprites {
+		bundleSprites = append(bundleSprites, BundleSprite{
+			Id:     s.Id(),
+			Name:   s.Name(),
+			Width:  s.Bounds().Dx(),
+			Height: s.Bounds().Dy(),
+		})
+	}
+	return
+}
+
+// .odoriの中身を読み込む
+// ファイル名に依存しないように、決まった名前のマニフェストとスプライトシートを使う
+func ReadBundle(fsys fs.FS) (animationP AnimationP, sprites []sprite.Sprite, err error) {
+	animationP, sprites, err = readProject(fsys, BundleManifestName, func(AnimationP) string {
+		return BundleSheetName
+	})
+	if err != nil {
+		return
+	}
+	bytes, err := fs.ReadFile(fsys, BundleSpritesName)
+	if err != nil {
+		return
+	}
+	bundleSprites := []BundleSprite{}
+	err = json.Unmarshal(bytes, &bundleSprites)
+	if err != nil {
+		return
+	}
+	// 名前はパーツにも保存されているが、スプライトの情報を優先する
+	names := map[string]string{}
+	for _, s := range bundleSprites {
+		if s.Name != "" {
+			names[s.Id] = s.Name
+		}
+	}
+	for i, s := range sprites {
+		if name, ok := names[s.Id()]; ok {
+			sprites[i] = s.WithName(name)
+		}
+	}
+	for _, part := range animationP.Animation.Parts {
+		if name, ok := names[part.Sprite.Id()]; ok {
+			part.Sprite = part.Sprite.WithName(name)
+		}
+	}
+	return
+}
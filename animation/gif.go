@@ -0,0 +1,361 @@
+package animation
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/soniakeys/quant/median"
+	xdraw "golang.org/x/image/draw"
+)
+
+type Dithering string
+
+const (
+	DitheringNone           Dithering = "none"
+	DitheringFloydSteinberg Dithering = "floyd-steinberg"
+	DitheringOrdered        Dithering = "ordered"
+)
+
+// GIF出力時の設定
+type GifOptions struct {
+	// gif.GIFのLoopCountと同じく、0で無限ループ、-1でループしない
+	LoopCount int `json:"loopCount"`
+	// 全フレームで共通のパレットを使う
+	GlobalPalette bool      `json:"globalPalette"`
+	Dithering     Dithering `json:"dithering"`
+	// nilの場合は背景を透過する
+	Background *color.RGBA `json:"background,omitempty"`
+	// アルファ値がこれ未満の画素は透過、それ以外は不透明にする
+	AlphaThreshold uint8 `json:"alphaThreshold"`
+	// 整数倍で拡大する
+	Scale int `json:"scale"`
+	// 連続する同じ見た目のフレームを1枚にまとめる
+	MergeIdenticalFrames bool `json:"mergeIdenticalFrames"`
+}
+
+// 多くのブラウザはこれより短いDelayを正しく扱わない(1/100秒単位)
+const MinimumGifDelay = 2
+
+func DefaultGifOptions() GifOptions {
+	return GifOptions{
+		LoopCount:            0,
+		GlobalPalette:        false,
+		Dithering:            DitheringNone,
+		Background:           nil,
+		AlphaThreshold:       128,
+		Scale:                1,
+		MergeIdenticalFrames: false,
+	}
+}
+
+// ダイアログで編集するための文字列表現
+func (o GifOptions) String() string {
+	palette := "local"
+	if o.GlobalPalette {
+		palette = "global"
+	}
+	bg := "transparent"
+	if o.Background != nil {
+		bg = fmt.Sprintf("#%02x%02x%02x", o.Background.R, o.Background.G, o.Background.B)
+	}
+	return fmt.Sprintf("loop=%d palette=%s dither=%s bg=%s alpha=%d scale=%d merge=%t", o.LoopCount, palette, o.Dithering, bg, o.AlphaThreshold, o.Scale, o.MergeIdenticalFrames)
+}
+
+// "loop=0 palette=local ..."の形式を解釈する
+// 指定されなかった項目はデフォルト値になる
+func ParseGifOptions(s string) (o GifOptions, err error) {
+	o = DefaultGifOptions()
+	for _, field := range strings.Fields(s) {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			err = fmt.Errorf("invalid option: %s", field)
+			return
+		}
+		switch key {
+		case "loop":
+			o.LoopCount, err = strconv.Atoi(value)
+			if err == nil && o.LoopCount < -1 {
+				err = errors.New("loop must be -1 or greater")
+			}
+		case "palette":
+			switch value {
+			case "local":
+				o.GlobalPalette = false
+			case "global":
+				o.GlobalPalette = true
+			default:
+				err = fmt.Errorf("palette must be local or global: %s", value)
+			}
+		case "dither":
+			switch Dithering(value) {
+			case DitheringNone, DitheringFloydSteinberg, DitheringOrdered:
+				o.Dithering = Dithering(value)
+			default:
+				err = fmt.Errorf("dither must be none, floyd-steinberg or ordered: %s", value)
+			}
+		case "bg":
+			if value == "transparent" {
+				o.Background = nil
+			} else {
+				var r, g, b uint8
+				_, err = fmt.Sscanf(value, "#%02x%02x%02x", &r, &g, &b)
+				if err == nil {
+					o.Background = &color.RGBA{R: r, G: g, B: b, A: 255}
+				} else {
+					err = fmt.Errorf("bg must be transparent or #rrggbb: %s", value)
+				}
+			}
+		case "alpha":
+			var alpha int
+			alpha, err = strconv.Atoi(value)
+			if err == nil && (alpha < 0 || alpha > 255) {
+				err = errors.New("alpha must be between 0 and 255")
+			}
+			o.AlphaThreshold = uint8(alpha)
+		case "scale":
+			o.Scale, err = strconv.Atoi(value)
+			if err == nil && o.Scale < 1 {
+				err = errors.New("scale must be 1 or greater")
+			}
+		case "merge":
+			o.MergeIdenticalFrames, err = strconv.ParseBool(value)
+		default:
+			err = fmt.Errorf("unknown option: %s", key)
+		}
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// MinimumGifDelayより短くなったフレームの番号を返す
+// 出力自体は行われるので、呼び出し側で警告する想定
+func (a *Animation) ExportAsGif(path string, opts GifOptions, tps int) (shortFrames []int, err error) {
+	if !a.CanExport() {
+		err = errors.New("can not export")
+		return
+	}
+	scale := opts.Scale
+	if scale < 1 {
+		scale = 1
+	}
+	bounds := image.Rect(0, 0, a.Width*scale, a.Height*scale)
+	frames := []*image.RGBA{}
+	lengths := []int{}
+	for _, part := range a.Parts {
+		frame := a.RenderFrame(part)
+		if scale > 1 {
+			scaled := image.NewRGBA(bounds)
+			xdraw.NearestNeighbor.Scale(scaled, bounds, frame, frame.Bounds(), xdraw.Src, nil)
+			frame = scaled
+		}
+		frame = flattenFrame(frame, opts)
+		// 直前のフレームと同じ見た目なら長さだけ足す
+		if last := len(frames) - 1; opts.MergeIdenticalFrames && last >= 0 && bytes.Equal(frames[last].Pix, frame.Pix) {
+			lengths[last] += part.Length
+			continue
+		}
+		frames = append(frames, frame)
+		lengths = append(lengths, part.Length)
+	}
+	// 透過する場合は0番目を透明色にする
+	numColors := 256
+	if opts.Background == nil {
+		numColors = 255
+	}
+	var globalPalette color.Palette
+	if opts.GlobalPalette {
+		globalPalette = gifPalette(frames, numColors, opts)
+	}
+	outGif := &gif.GIF{
+		LoopCount: opts.LoopCount,
+	}
+	if opts.GlobalPalette {
+		outGif.Config = image.Config{
+			ColorModel: globalPalette,
+			Width:      bounds.Dx(),
+			Height:     bounds.Dy(),
+		}
+	}
+	delays := GifDelays(lengths, tps)
+	for i, frame := range frames {
+		p := globalPalette
+		if !opts.GlobalPalette {
+			p = gifPalette(frames[i:i+1], numColors, opts)
+		}
+		outGif.Image = append(outGif.Image, ditherFrame(frame, p, opts.Dithering))
+		outGif.Delay = append(outGif.Delay, delays[i])
+		outGif.Disposal = append(outGif.Disposal, gif.DisposalBackground)
+		if delays[i] < MinimumGifDelay {
+			shortFrames = append(shortFrames, i)
+		}
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	err = gif.EncodeAll(file, outGif)
+	return
+}
+
+// Tick数の列をGIFのDelay(1/100秒)の列に変換する
+func GifDelays(lengths []int, tps int) (delays []int) {
+	return ConvertDurations(lengths, tps, 100, 0)
+}
+
+// 背景色で塗りつぶすか、閾値でアルファ値を0か255にする
+// 返り値はアルファ値が0か255のみになる
+func flattenFrame(frame *image.RGBA, opts GifOptions) *image.RGBA {
+	flat := image.NewRGBA(frame.Bounds())
+	if opts.Background != nil {
+		draw.Draw(flat, flat.Rect, image.NewUniform(*opts.Background), image.Point{}, draw.Src)
+		draw.Draw(flat, flat.Rect, frame, frame.Rect.Min, draw.Over)
+		return flat
+	}
+	for i := 0; i < len(frame.Pix); i += 4 {
+		alpha := frame.Pix[i+3]
+		if alpha == 0 || alpha < opts.AlphaThreshold {
+			continue
+		}
+		// 乗算済みアルファを戻して不透明にする
+		for c := 0; c < 3; c++ {
+			flat.Pix[i+c] = uint8(int(frame.Pix[i+c]) * 255 / int(alpha))
+		}
+		flat.Pix[i+3] = 255
+	}
+	return flat
+}
+
+// 不透明な画素から減色したパレットを作る
+func gifPalette(frames []*image.RGBA, numColors int, opts GifOptions) color.Palette {
+	p := color.Palette{}
+	if opts.Background == nil {
+		p = append(p, image.Transparent)
+	}
+	opaque := 0
+	for _, frame := range frames {
+		for i := 3; i < len(frame.Pix); i += 4 {
+			if frame.Pix[i] == 255 {
+				opaque++
+			}
+		}
+	}
+	if opaque == 0 {
+		return p
+	}
+	// median.Quantizerはアルファ値を見ないので、不透明な画素だけを集める
+	pixels := image.NewRGBA(image.Rect(0, 0, opaque, 1))
+	j := 0
+	for _, frame := range frames {
+		for i := 0; i < len(frame.Pix); i += 4 {
+			if frame.Pix[i+3] == 255 {
+				copy(pixels.Pix[j:j+4], frame.Pix[i:i+4])
+				j += 4
+			}
+		}
+	}
+	q := median.Quantizer(numColors)
+	return append(p, q.Quantize(make(color.Palette, 0, numColors), pixels)...)
+}
+
+// 4x4のベイヤー行列
+var bayer4x4 = [4][4]float64{
+	{0, 8, 2, 10},
+	{12, 4, 14, 6},
+	{3, 11, 1, 9},
+	{15, 7, 13, 5},
+}
+
+// 透過した画素はパレットの0番目にし、不透明な画素は指定の方法で減色する
+func ditherFrame(frame *image.RGBA, p color.Palette, dithering Dithering) *image.Paletted {
+	paletted := image.NewPaletted(frame.Rect, p)
+	// 透明色を除いたパレット
+	offset := 0
+	if len(p) > 0 {
+		if _, _, _, a := p[0].RGBA(); a == 0 {
+			offset = 1
+		}
+	}
+	opaque := p[offset:]
+	if len(opaque) == 0 {
+		return paletted
+	}
+	cache := map[[3]int]uint8{}
+	nearest := func(r, g, b int) uint8 {
+		key := [3]int{r, g, b}
+		if index, ok := cache[key]; ok {
+			return index
+		}
+		index := uint8(opaque.Index(color.RGBA{R: uint8(r), G: uint8(g), B: uint8(b), A: 255}) + offset)
+		cache[key] = index
+		return index
+	}
+	w, h := frame.Rect.Dx(), frame.Rect.Dy()
+	// Floyd–Steinberg法の誤差
+	var errCur, errNext [][3]float64
+	if dithering == DitheringFloydSteinberg {
+		errCur = make([][3]float64, w+2)
+		errNext = make([][3]float64, w+2)
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := y*frame.Stride + x*4
+			if frame.Pix[i+3] != 255 {
+				paletted.Pix[y*paletted.Stride+x] = 0
+				continue
+			}
+			c := [3]float64{float64(frame.Pix[i]), float64(frame.Pix[i+1]), float64(frame.Pix[i+2])}
+			switch dithering {
+			case DitheringFloydSteinberg:
+				for k := range c {
+					c[k] += errCur[x+1][k]
+				}
+			case DitheringOrdered:
+				d := (bayer4x4[y%4][x%4]/16 - 0.5) * 32
+				for k := range c {
+					c[k] += d
+				}
+			}
+			index := nearest(clamp255(c[0]), clamp255(c[1]), clamp255(c[2]))
+			paletted.Pix[y*paletted.Stride+x] = index
+			if dithering == DitheringFloydSteinberg {
+				r, g, b, _ := p[index].RGBA()
+				q := [3]float64{float64(r >> 8), float64(g >> 8), float64(b >> 8)}
+				for k := range c {
+					e := c[k] - q[k]
+					errCur[x+2][k] += e * 7 / 16
+					errNext[x][k] += e * 3 / 16
+					errNext[x+1][k] += e * 5 / 16
+					errNext[x+2][k] += e * 1 / 16
+				}
+			}
+		}
+		if dithering == DitheringFloydSteinberg {
+			errCur, errNext = errNext, errCur
+			for x := range errNext {
+				errNext[x] = [3]float64{}
+			}
+		}
+	}
+	return paletted
+}
+
+func clamp255(v float64) int {
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+	return int(v + 0.5)
+}
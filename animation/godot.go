@@ -0,0 +1,85 @@
+package animation
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Godot 4のSpriteFrames(.tres)を作る
+// imagePathは.tresから見たスプライトシートのパスで、SpriteSheetの位置ごとにAtlasTextureを作る
+// Godotでは全てのフレームが中心に揃えられるため、拡大縮小・反転・位置の補正があるパーツの番号をinexactPartsとして返す
+func NewGodotSpriteFrames(animationP AnimationP, imagePath string, tps int) (tres string, inexactParts []int) {
+	a := animationP.Animation
+	// 全てのパーツの長さを整数倍で表せる単位をspeedの基準にする
+	unit := 0
+	for _, part := range a.Parts {
+		unit = gcd(unit, part.Length)
+	}
+	if unit <= 0 {
+		unit = 1
+	}
+
+	// 使われている順に並べ、使われていないものはIDの順で後ろに置く
+	spriteIds := []string{}
+	for _, s := range a.Sprites() {
+		if _, ok := animationP.SpriteSheet[s.Id()]; ok {
+			spriteIds = append(spriteIds, s.Id())
+		}
+	}
+	unused := []string{}
+	for id := range animationP.SpriteSheet {
+		if !slices.Contains(spriteIds, id) {
+			unused = append(unused, id)
+		}
+	}
+	slices.Sort(unused)
+	spriteIds = append(spriteIds, unused...)
+
+	ids := map[string]string{}
+	subResources := &strings.Builder{}
+	for _, spriteId := range spriteIds {
+		rect := animationP.SpriteSheet[spriteId]
+		id := fmt.Sprintf("AtlasTexture_%d", len(ids)+1)
+		ids[spriteId] = id
+		fmt.Fprintf(subResources, "[sub_resource type=\"AtlasTexture\" id=\"%s\"]\n", id)
+		fmt.Fprintf(subResources, "atlas = ExtResource(\"1_sheet\")\n")
+		fmt.Fprintf(subResources, "region = Rect2(%d, %d, %d, %d)\n\n", rect.Min.X, rect.Min.Y, rect.Dx(), rect.Dy())
+	}
+
+	frames := []string{}
+	for i, part := range a.Parts {
+		texture := "null"
+		if id, ok := ids[part.Sprite.Id()]; ok && !part.Sprite.IsEmpty() {
+			texture = fmt.Sprintf("SubResource(\"%s\")", id)
+			if part.Scale != 1 || part.Reverse || part.DiffX != 0 || part.DiffY != 0 {
+				inexactParts = append(inexactParts, i)
+			}
+		}
+		frames = append(frames, fmt.Sprintf("{\n\"duration\": %s,\n\"texture\": %s\n}", godotFloat(float64(part.Length)/float64(unit)), texture))
+	}
+
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "[gd_resource type=\"SpriteFrames\" load_steps=%d format=3]\n\n", len(ids)+2)
+	fmt.Fprintf(b, "[ext_resource type=\"Texture2D\" path=%s id=\"1_sheet\"]\n\n", strconv.Quote(imagePath))
+	b.WriteString(subResources.String())
+	b.WriteString("[resource]\n")
+	b.WriteString("animations = [{\n")
+	fmt.Fprintf(b, "\"frames\": [%s],\n", strings.Join(frames, ", "))
+	b.WriteString("\"loop\": true,\n")
+	fmt.Fprintf(b, "\"name\": &%s,\n", strconv.Quote(animationP.Name))
+	fmt.Fprintf(b, "\"speed\": %s\n", godotFloat(float64(tps)/float64(unit)))
+	b.WriteString("}]\n")
+	tres = b.String()
+	return
+}
+
+// Godotは小数点のない数値を整数として扱うので、必ず小数点を付ける
+func godotFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	if !strings.Contains(s, ".") {
+		s += ".0"
+	}
+	return s
+}
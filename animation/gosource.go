@@ -0,0 +1,189 @@
+package animation
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/token"
+	"image"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// 生成するパッケージで共通の型を定義するファイル名
+const GoTypesFileName = "odori_types.go"
+
+var goTypesTemplate = template.Must(template.New("types").Parse(`// Code generated by odori. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"bytes"
+	"image"
+	_ "image/png"
+)
+
+type Part struct {
+	// スプライトシート内の位置
+	Rect image.Rectangle
+	// スプライトがない場合はtrue
+	Empty   bool
+	Scale   float64
+	DiffX   int
+	DiffY   int
+	Reverse bool
+	// 表示するTick数
+	Length int
+}
+
+type Animation struct {
+	Name   string
+	Width  int
+	Height int
+	// Lengthの基準になるTPS
+	TPS   int
+	Sheet []byte
+	Parts []Part
+}
+
+// 埋め込まれたスプライトシートを読み込む
+func (a *Animation) SheetImage() (image.Image, error) {
+	img, _, err := image.Decode(bytes.NewReader(a.Sheet))
+	return img, err
+}
+
+// 全体のTick数
+func (a *Animation) MaxTick() (maxTick int) {
+	for _, part := range a.Parts {
+		maxTick += part.Length
+	}
+	return
+}
+
+// tickの時点で表示されるパーツの添字
+// パーツがない場合は-1
+func (a *Animation) PartIndexAt(tick int) int {
+	start := 0
+	for i, part := range a.Parts {
+		start += part.Length
+		if tick < start {
+			return i
+		}
+	}
+	return len(a.Parts) - 1
+}
+`))
+
+var goSourceTemplate = template.Must(template.New("source").Parse(`// Code generated by odori. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	_ "embed"
+	"image"
+)
+
+{{if .SheetName}}//go:embed {{.SheetName}}
+var {{.Var}}Sheet []byte
+{{else}}var {{.Var}}Sheet []byte
+{{end}}
+const {{.Ident}}TPS = {{.TPS}}
+
+// {{.Name}}のアニメーションを返す
+func New{{.Ident}}() *Animation {
+	return &Animation{
+		Name:   {{printf "%q" .Name}},
+		Width:  {{.Width}},
+		Height: {{.Height}},
+		TPS:    {{.Ident}}TPS,
+		Sheet:  {{.Var}}Sheet,
+		Parts: []Part{
+{{range .Parts}}			{Rect: image.Rect({{.Rect.Min.X}}, {{.Rect.Min.Y}}, {{.Rect.Max.X}}, {{.Rect.Max.Y}}), Empty: {{.Empty}}, Scale: {{.Scale}}, DiffX: {{.DiffX}}, DiffY: {{.DiffY}}, Reverse: {{.Reverse}}, Length: {{.Length}}},
+{{end}}		},
+	}
+}
+`))
+
+type goSourcePart struct {
+	Rect    image.Rectangle
+	Empty   bool
+	Scale   float64
+	DiffX   int
+	DiffY   int
+	Reverse bool
+	Length  int
+}
+
+// go:embedでスプライトシートを埋め込むGoのソースコードを作る
+// typesはパッケージで共通の型で、sourceはアニメーションごとのコンストラクタ
+// sheetNameが空の場合はスプライトシートを埋め込まない
+func NewGoSource(animationP AnimationP, rectsMap map[string]image.Rectangle, pkg, sheetName string, tps int) (types []byte, source []byte, err error) {
+	if !token.IsIdentifier(pkg) || token.IsKeyword(pkg) {
+		err = fmt.Errorf("invalid package name: %s", pkg)
+		return
+	}
+	if animationP.Name == "" {
+		err = errors.New("name is empty")
+		return
+	}
+	a := animationP.Animation
+	ident := []rune(animationP.Name)
+	ident[0] = unicode.ToUpper(ident[0])
+	// 数字から始まる名前は識別子にできない
+	if unicode.IsDigit(ident[0]) {
+		ident = append([]rune("Animation"), ident...)
+	}
+	parts := []goSourcePart{}
+	for _, part := range a.Parts {
+		rect, ok := rectsMap[part.Sprite.Id()]
+		parts = append(parts, goSourcePart{
+			Rect:    rect,
+			Empty:   !ok || part.Sprite.IsEmpty(),
+			Scale:   part.Scale,
+			DiffX:   part.DiffX,
+			DiffY:   part.DiffY,
+			Reverse: part.Reverse,
+			Length:  part.Length,
+		})
+	}
+
+	buf := &bytes.Buffer{}
+	err = goTypesTemplate.Execute(buf, struct{ Package string }{pkg})
+	if err != nil {
+		return
+	}
+	types, err = format.Source(buf.Bytes())
+	if err != nil {
+		return
+	}
+
+	buf.Reset()
+	err = goSourceTemplate.Execute(buf, struct {
+		Package   string
+		Name      string
+		Ident     string
+		Var       string
+		SheetName string
+		Width     int
+		Height    int
+		TPS       int
+		Parts     []goSourcePart
+	}{
+		Package:   pkg,
+		Name:      animationP.Name,
+		Ident:     string(ident),
+		Var:       strings.ToLower(string(ident[:1])) + string(ident[1:]),
+		SheetName: sheetName,
+		Width:     a.Width,
+		Height:    a.Height,
+		TPS:       tps,
+		Parts:     parts,
+	})
+	if err != nil {
+		return
+	}
+	source, err = format.Source(buf.Bytes())
+	return
+}
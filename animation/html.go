@@ -0,0 +1,184 @@
+package animation
+
+import (
+	"bytes"
+	"encoding/base64"
+	"html/template"
+	"image"
+)
+
+// HTMLのプレビューに埋め込むデータ
+type htmlPreviewData struct {
+	Name   string            `json:"name"`
+	Width  int               `json:"width"`
+	Height int               `json:"height"`
+	TPS    int               `json:"tps"`
+	Sheet  string            `json:"sheet"`
+	Parts  []htmlPreviewPart `json:"parts"`
+}
+
+type htmlPreviewPart struct {
+	// スプライトシート内の位置
+	// 空のパーツの場合はnull
+	Source *[4]int `json:"source"`
+	// キャンバスに描画する際の変換行列(canvasのsetTransformの引数の順)
+	Transform [6]float64 `json:"transform"`
+	Length    int        `json:"length"`
+}
+
+var htmlPreviewTemplate = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Name}} - Odori</title>
+<style>
+body { font-family: sans-serif; background: #e6e6e6; margin: 16px; }
+canvas {
+  image-rendering: pixelated;
+  background-color: #fff;
+  background-image: linear-gradient(45deg, #c8c8c8 25%, transparent 25%, transparent 75%, #c8c8c8 75%), linear-gradient(45deg, #c8c8c8 25%, transparent 25%, transparent 75%, #c8c8c8 75%);
+  background-size: 20px 20px;
+  background-position: 0 0, 10px 10px;
+  border: 1px solid #000;
+}
+.controls { margin-top: 8px; display: flex; gap: 8px; align-items: center; }
+</style>
+</head>
+<body>
+<h1>{{.Name}}</h1>
+<canvas id="canvas"></canvas>
+<div class="controls">
+<button id="prev">&lt;</button>
+<button id="play">Pause</button>
+<button id="next">&gt;</button>
+<label>Zoom <select id="zoom"><option>1</option><option selected>2</option><option>4</option><option>8</option></select></label>
+<span id="status"></span>
+</div>
+<script>
+(function () {
+  var data = {{.Data}};
+  var canvas = document.getElementById("canvas");
+  var ctx = canvas.getContext("2d");
+  var playButton = document.getElementById("play");
+  var status = document.getElementById("status");
+  var zoomSelect = document.getElementById("zoom");
+  var sheet = new Image();
+  var indexes = [];
+  var maxTick = 0;
+  data.parts.forEach(function (part) {
+    indexes.push(maxTick);
+    maxTick += part.length;
+  });
+  var tick = 0;
+  var playing = true;
+  var elapsed = 0;
+  var last = null;
+  var perTick = 1000 / data.tps;
+
+  function partIndex() {
+    var index = 0;
+    for (var i = 0; i < indexes.length; i++) {
+      if (tick < indexes[i]) break;
+      index = i;
+    }
+    return index;
+  }
+
+  function resize() {
+    var zoom = Number(zoomSelect.value);
+    canvas.width = data.width * zoom;
+    canvas.height = data.height * zoom;
+    draw();
+  }
+
+  function draw() {
+    var zoom = Number(zoomSelect.value);
+    ctx.setTransform(1, 0, 0, 1, 0, 0);
+    ctx.clearRect(0, 0, canvas.width, canvas.height);
+    ctx.imageSmoothingEnabled = false;
+    var index = partIndex();
+    var part = data.parts[index];
+    if (part && part.source && sheet.complete) {
+      var m = part.transform;
+      ctx.setTransform(m[0] * zoom, m[1] * zoom, m[2] * zoom, m[3] * zoom, m[4] * zoom, m[5] * zoom);
+      var s = part.source;
+      ctx.drawImage(sheet, s[0], s[1], s[2], s[3], 0, 0, s[2], s[3]);
+    }
+    status.textContent = "Part " + (index + 1) + "/" + data.parts.length + "  Tick " + (tick + 1) + "/" + maxTick;
+  }
+
+  function step(delta) {
+    var index = (partIndex() + delta + data.parts.length) % data.parts.length;
+    tick = indexes[index];
+    elapsed = 0;
+    draw();
+  }
+
+  function loop(now) {
+    if (last !== null && playing) {
+      elapsed += now - last;
+      while (elapsed >= perTick) {
+        elapsed -= perTick;
+        tick = (tick + 1) % maxTick;
+      }
+      draw();
+    }
+    last = now;
+    requestAnimationFrame(loop);
+  }
+
+  playButton.addEventListener("click", function () {
+    playing = !playing;
+    elapsed = 0;
+    playButton.textContent = playing ? "Pause" : "Play";
+  });
+  document.getElementById("prev").addEventListener("click", function () { step(-1); });
+  document.getElementById("next").addEventListener("click", function () { step(1); });
+  zoomSelect.addEventListener("change", resize);
+  sheet.onload = draw;
+  if (data.sheet) sheet.src = data.sheet;
+  resize();
+  requestAnimationFrame(loop);
+})();
+</script>
+</body>
+</html>
+`))
+
+// スプライトシートを埋め込んだ単体で動くHTMLのプレビューを作る
+// sheetPngはスプライトシートのPNG、rectsMapはその中の位置
+func NewHtmlPreview(animationP AnimationP, sheetPng []byte, rectsMap map[string]image.Rectangle, tps int) (html []byte, err error) {
+	a := animationP.Animation
+	data := htmlPreviewData{
+		Name:   animationP.Name,
+		Width:  a.Width,
+		Height: a.Height,
+		TPS:    tps,
+		Parts:  []htmlPreviewPart{},
+	}
+	if len(sheetPng) > 0 {
+		data.Sheet = "data:image/png;base64," + base64.StdEncoding.EncodeToString(sheetPng)
+	}
+	for _, part := range a.Parts {
+		p := htmlPreviewPart{
+			Length: part.Length,
+		}
+		if rect, ok := rectsMap[part.Sprite.Id()]; ok && !part.Sprite.IsEmpty() {
+			p.Source = &[4]int{rect.Min.X, rect.Min.Y, rect.Dx(), rect.Dy()}
+			// canvasのsetTransform(a, b, c, d, e, f)は x' = a*x + c*y + e, y' = b*x + d*y + f
+			m := a.Transform(part)
+			p.Transform = [6]float64{m[0], m[3], m[1], m[4], m[2], m[5]}
+		}
+		data.Parts = append(data.Parts, p)
+	}
+	buf := &bytes.Buffer{}
+	err = htmlPreviewTemplate.Execute(buf, struct {
+		Name string
+		Data htmlPreviewData
+	}{
+		Name: animationP.Name,
+		Data: data,
+	})
+	html = buf.Bytes()
+	return
+}
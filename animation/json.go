@@ -0,0 +1,22 @@
+package animation
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// 書き出すJSONの共通の形式
+// 同じ内容なら同じバイト列になるように、インデントと末尾の改行を揃える
+// マップのキーはencoding/jsonがソートする
+func MarshalJson(v any) (data []byte, err error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(v)
+	if err != nil {
+		return
+	}
+	data = buf.Bytes()
+	return
+}
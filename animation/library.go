@@ -0,0 +1,93 @@
+package animation
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/aethiopicuschan/odori/sprite"
+)
+
+// Explorerに並んでいるスプライトの永続化モデル
+type LibraryEntry struct {
+	Id   string `json:"id"`
+	Name string `json:"name,omitempty"`
+	// パーツで使われているかどうか
+	Used bool `json:"used"`
+}
+
+// Explorerのスプライトとパーツで使われているスプライトをまとめる
+// spritesはスプライトシートに含めるスプライトで、libraryの順番の後にlibraryにない使われているスプライトが続く
+func NewLibrary(library []sprite.Sprite, a *Animation) (sprites []sprite.Sprite, entries []LibraryEntry) {
+	used := map[string]bool{}
+	for _, s := range a.Sprites() {
+		used[s.Id()] = true
+	}
+	candidates := append([]sprite.Sprite{}, library...)
+	candidates = append(candidates, a.Sprites()...)
+	added := map[string]bool{}
+	for _, s := range candidates {
+		if s.IsEmpty() || added[s.Id()] {
+			continue
+		}
+		added[s.Id()] = true
+		sprites = append(sprites, s)
+		entries = append(entries, LibraryEntry{
+			Id:   s.Id(),
+			Name: s.Name(),
+			Used: used[s.Id()],
+		})
+	}
+	return
+}
+
+// スプライトシートから読み込んだスプライトをExplorerに並べる順番にする
+// libraryがない古い形式ではパーツで使われている順番にする
+func orderLibrary(sprites []sprite.Sprite, animationP AnimationP) (ordered []sprite.Sprite, err error) {
+	byId := map[string]sprite.Sprite{}
+	for _, s := range sprites {
+		byId[s.Id()] = s
+	}
+	added := map[string]bool{}
+	if animationP.Library != nil {
+		for _, entry := range animationP.Library {
+			s, ok := byId[entry.Id]
+			if !ok {
+				err = fmt.Errorf("sprite %s is not found in the sprite sheet", entry.Id)
+				return
+			}
+			if added[entry.Id] {
+				continue
+			}
+			added[entry.Id] = true
+			if entry.Name != "" {
+				s = s.WithName(entry.Name)
+			}
+			ordered = append(ordered, s)
+		}
+	} else {
+		for _, part := range animationP.Animation.Parts {
+			if s, ok := byId[part.Sprite.Id()]; ok && !added[s.Id()] {
+				added[s.Id()] = true
+				ordered = append(ordered, s)
+			}
+		}
+	}
+	// どちらにも含まれないものはIDの順で後ろに置く
+	rest := []sprite.Sprite{}
+	for _, s := range sprites {
+		if !added[s.Id()] {
+			rest = append(rest, s)
+		}
+	}
+	slices.SortFunc(rest, func(a, b sprite.Sprite) int {
+		if a.Id() < b.Id() {
+			return -1
+		}
+		if a.Id() > b.Id() {
+			return 1
+		}
+		return 0
+	})
+	ordered = append(ordered, rest...)
+	return
+}
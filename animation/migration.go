@@ -0,0 +1,63 @@
+package animation
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/aethiopicuschan/odori/constant"
+)
+
+// migrations[i]はformatVersionがiのJSONをi+1に変換する
+// 形式を変える場合はconstant.FormatVersionを上げ、ここに変換を追加する
+var migrations = []func(raw map[string]any) error{
+	migrateFrom0,
+	migrateFrom1,
+}
+
+// バージョンが付く前のJSON
+// バージョン1はformatVersionを付けただけで内容は同じなので変換は不要
+func migrateFrom0(raw map[string]any) error {
+	return nil
+}
+
+// バージョン2でlibraryが追加された
+// libraryがない場合はパーツで使われているスプライトだけを読み込むので変換は不要だが、
+// 古いOdoriで開いて保存すると使われていないスプライトが失われるのでバージョンを上げている
+func migrateFrom1(raw map[string]any) error {
+	return nil
+}
+
+// 古い形式のJSONを現在の形式に変換する
+func migrate(bytes []byte) (migrated []byte, err error) {
+	raw := map[string]any{}
+	err = json.Unmarshal(bytes, &raw)
+	if err != nil {
+		return
+	}
+	version := 0
+	if v, ok := raw["formatVersion"]; ok {
+		f, ok := v.(float64)
+		if !ok || f < 0 || f != float64(int(f)) {
+			err = fmt.Errorf("invalid formatVersion: %v", v)
+			return
+		}
+		version = int(f)
+	}
+	if version > constant.FormatVersion {
+		err = fmt.Errorf("this file was saved by a newer version of Odori (format version %d, supported up to %d)", version, constant.FormatVersion)
+		return
+	}
+	if version == constant.FormatVersion {
+		return bytes, nil
+	}
+	for ; version < constant.FormatVersion; version++ {
+		err = migrations[version](raw)
+		if err != nil {
+			err = fmt.Errorf("migration from format version %d: %w", version, err)
+			return
+		}
+	}
+	raw["formatVersion"] = version
+	migrated, err = json.Marshal(raw)
+	return
+}
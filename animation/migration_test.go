@@ -0,0 +1,58 @@
+package animation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/aethiopicuschan/odori/constant"
+)
+
+// バージョンが付く前のOdoriが保存していたJSON
+const projectV0 = `{"name":"walk","animation":{"width":8,"height":8,"parts":[{"sprite":{"id":"a"},"scale":2,"diffX":1,"diffY":-1,"reverse":true,"length":30}]},"spriteSheet":{"a":{"Min":{"X":0,"Y":0},"Max":{"X":4,"Y":3}}}}`
+
+func TestMigrateFrom0(t *testing.T) {
+	migrated, err := migrate([]byte(projectV0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]any{}
+	if err := json.Unmarshal(migrated, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{}
+	if err := json.Unmarshal([]byte(projectV0), &want); err != nil {
+		t.Fatal(err)
+	}
+	want["formatVersion"] = float64(constant.FormatVersion)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("migrate() = %s, want %v", migrated, want)
+	}
+}
+
+func TestMigrateCurrentVersion(t *testing.T) {
+	data := []byte(fmt.Sprintf(`{"formatVersion":%d,"name":"walk","animation":{"width":8,"height":8,"parts":[]},"library":[]}`, constant.FormatVersion))
+	migrated, err := migrate(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(migrated, data) {
+		t.Fatalf("migrate() = %s, want %s", migrated, data)
+	}
+}
+
+func TestMigrateInvalidVersion(t *testing.T) {
+	tests := []string{
+		fmt.Sprintf(`{"formatVersion":%d}`, constant.FormatVersion+1),
+		`{"formatVersion":-1}`,
+		`{"formatVersion":1.5}`,
+		`{"formatVersion":"2"}`,
+	}
+	for _, data := range tests {
+		if _, err := migrate([]byte(data)); err == nil {
+			t.Errorf("migrate(%s) succeeded", data)
+		}
+	}
+}
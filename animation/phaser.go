@@ -0,0 +1,148 @@
+package animation
+
+import (
+	"fmt"
+	"image"
+	"slices"
+)
+
+// Phaser 3のマルチアトラス形式のJSON
+type PhaserAtlas struct {
+	Textures []PhaserTexture `json:"textures"`
+	Meta     PhaserMeta      `json:"meta"`
+}
+
+type PhaserTexture struct {
+	Image  string        `json:"image"`
+	Format string        `json:"format"`
+	Size   AsepriteSize  `json:"size"`
+	Scale  float64       `json:"scale"`
+	Frames []PhaserFrame `json:"frames"`
+}
+
+type PhaserFrame struct {
+	Filename         string       `json:"filename"`
+	Rotated          bool         `json:"rotated"`
+	Trimmed          bool         `json:"trimmed"`
+	SourceSize       AsepriteSize `json:"sourceSize"`
+	SpriteSourceSize AsepriteRect `json:"spriteSourceSize"`
+	Frame            AsepriteRect `json:"frame"`
+}
+
+type PhaserMeta struct {
+	App     string `json:"app"`
+	Version string `json:"version"`
+}
+
+// this.anims.fromJSONで読み込めるJSON
+type PhaserAnimations struct {
+	Anims           []PhaserAnimation `json:"anims"`
+	GlobalTimeScale float64           `json:"globalTimeScale"`
+}
+
+type PhaserAnimation struct {
+	Key       string                 `json:"key"`
+	Type      string                 `json:"type"`
+	Frames    []PhaserAnimationFrame `json:"frames"`
+	FrameRate float64                `json:"frameRate"`
+	Repeat    int                    `json:"repeat"`
+}
+
+type PhaserAnimationFrame struct {
+	// テクスチャのキー
+	Key   string `json:"key"`
+	Frame string `json:"frame"`
+	// frameRateから決まる表示時間に加算するミリ秒
+	Duration int `json:"duration"`
+}
+
+// スプライトシートの位置情報からPhaser用のアトラスとアニメーションのJSONを作る
+// テクスチャのキーにはプロジェクト名を使う
+// Phaserの形式では拡大縮小と反転と空のフレームを表せないため、そのようなパーツの番号をinexactPartsとして返す
+func NewPhaserAtlas(animationP AnimationP, rectsMap map[string]image.Rectangle, imageName string, imageSize image.Point, tps int) (atlas PhaserAtlas, anims PhaserAnimations, inexactParts []int) {
+	a := animationP.Animation
+	texture := PhaserTexture{
+		Image:  imageName,
+		Format: "RGBA8888",
+		Size:   AsepriteSize{W: imageSize.X, H: imageSize.Y},
+		Scale:  1,
+		Frames: []PhaserFrame{},
+	}
+	// 同じスプライトでも置く位置が違えば別のフレームにする
+	type placedSprite struct {
+		id        string
+		placement image.Rectangle
+	}
+	frameNames := map[placedSprite]string{}
+	usedNames := []string{}
+	animation := PhaserAnimation{
+		Key:    animationP.Name,
+		Type:   "frame",
+		Frames: []PhaserAnimationFrame{},
+		Repeat: -1,
+	}
+	// 空のパーツは直前のフレームを延ばす
+	// 直前のフレームがなければ次のフレームに足す
+	isEmpty := func(i int) bool {
+		_, ok := rectsMap[a.Parts[i].Sprite.Id()]
+		return !ok || a.Parts[i].Sprite.IsEmpty()
+	}
+	durations := foldEmptyDurations(a.Durations(tps), isEmpty)
+	frameDurations := []int{}
+	for i, part := range a.Parts {
+		if isEmpty(i) {
+			inexactParts = append(inexactParts, i)
+			continue
+		}
+		rect := rectsMap[part.Sprite.Id()]
+		if part.Scale != 1 || part.Reverse {
+			inexactParts = append(inexactParts, i)
+		}
+		placement := a.Placement(part)
+		key := placedSprite{part.Sprite.Id(), placement}
+		name, ok := frameNames[key]
+		if !ok {
+			name = part.Sprite.Name()
+			if name == "" || slices.Contains(usedNames, name) {
+				name = fmt.Sprintf("%s_%d", animationP.Name, len(texture.Frames))
+			}
+			frameNames[key] = name
+			usedNames = append(usedNames, name)
+			texture.Frames = append(texture.Frames, PhaserFrame{
+				Filename:         name,
+				Trimmed:          placement != image.Rect(0, 0, a.Width, a.Height),
+				SourceSize:       AsepriteSize{W: a.Width, H: a.Height},
+				SpriteSourceSize: AsepriteRect{X: placement.Min.X, Y: placement.Min.Y, W: placement.Dx(), H: placement.Dy()},
+				Frame:            AsepriteRect{X: rect.Min.X, Y: rect.Min.Y, W: rect.Dx(), H: rect.Dy()},
+			})
+		}
+		animation.Frames = append(animation.Frames, PhaserAnimationFrame{
+			Key:   animationP.Name,
+			Frame: name,
+		})
+		frameDurations = append(frameDurations, durations[i])
+	}
+	// 最も短いフレームをframeRateの基準にし、残りは各フレームのdurationで補う
+	if len(frameDurations) > 0 {
+		shortest := slices.Min(frameDurations)
+		if shortest < 1 {
+			shortest = 1
+		}
+		animation.FrameRate = 1000 / float64(shortest)
+		for i, d := range frameDurations {
+			animation.Frames[i].Duration = max(d-shortest, 0)
+		}
+	}
+	atlas = PhaserAtlas{
+		Textures: []PhaserTexture{texture},
+		Meta: PhaserMeta{
+			App:     "https://github.com/aethiopicuschan/odori",
+			Version: "3.0",
+		},
+	}
+	anims = PhaserAnimations{
+		Anims:           []PhaserAnimation{animation},
+		GlobalTimeScale: 1,
+	}
+	return
+}
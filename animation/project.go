@@ -0,0 +1,102 @@
+package animation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/png"
+	"io/fs"
+	"path"
+
+	"github.com/aethiopicuschan/odori/sprite"
+)
+
+// パーツで使われているスプライトを重複なく返す
+func (a *Animation) Sprites() (sprites []sprite.Sprite) {
+	used := map[string]bool{}
+	for _, part := range a.Parts {
+		if part.Sprite.IsEmpty() || used[part.Sprite.Id()] {
+			continue
+		}
+		used[part.Sprite.Id()] = true
+		sprites = append(sprites, part.Sprite)
+	}
+	return
+}
+
+// ExportされたJSONとスプライトシートを読み込む
+// スプライトシートはJSONと同じディレクトリの"<name>.png"にある想定
+func ReadProject(fsys fs.FS, name string) (animationP AnimationP, sprites []sprite.Sprite, err error) {
+	return readProject(fsys, name, func(animationP AnimationP) string {
+		return path.Join(path.Dir(name), animationP.Name+".png")
+	})
+}
+
+// sheetPathはJSONの内容からスプライトシートのパスを返す
+func readProject(fsys fs.FS, name string, sheetPath func(animationP AnimationP) string) (animationP AnimationP, sprites []sprite.Sprite, err error) {
+	bytes, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return
+	}
+	bytes, err = migrate(bytes)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(bytes, &animationP)
+	if err != nil {
+		return
+	}
+	if animationP.Animation == nil {
+		err = errors.New("animation is missing")
+		return
+	}
+	if len(animationP.SpriteSheet) == 0 {
+		return
+	}
+	// スプライトシートの読み込み
+	file, err := fsys.Open(sheetPath(animationP))
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	si, _, err := image.Decode(file)
+	if err != nil {
+		return
+	}
+	sprites = sprite.NewSpritesFromRectMap(si, animationP.SpriteSheet)
+	// 名前はパーツ側に保存されている
+	names := map[string]string{}
+	for _, part := range animationP.Animation.Parts {
+		if name := part.Sprite.Name(); name != "" {
+			names[part.Sprite.Id()] = name
+		}
+	}
+	for i, s := range sprites {
+		if name, ok := names[s.Id()]; ok {
+			sprites[i] = s.WithName(name)
+		}
+	}
+	sprites, err = orderLibrary(sprites, animationP)
+	if err != nil {
+		return
+	}
+	for i, part := range animationP.Animation.Parts {
+		if part.Sprite.IsEmpty() {
+			continue
+		}
+		found := false
+		for _, s := range sprites {
+			if s.Id() == part.Sprite.Id() {
+				animationP.Animation.Parts[i].Sprite = s
+				found = true
+				break
+			}
+		}
+		if !found {
+			err = fmt.Errorf("sprite %s is not found in the sprite sheet", part.Sprite.Id())
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,65 @@
+package animation
+
+import (
+	"image"
+	"math"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+)
+
+// パーツをアニメーションのキャンバスに配置するための変換行列
+// プレビューと各種エクスポートで同じ計算を使うために共通化している
+func (a *Animation) Transform(part *Part) f64.Aff3 {
+	bounds := part.Sprite.Bounds()
+	w := float64(bounds.Dx())
+	h := float64(bounds.Dy())
+	scale := part.Scale
+	diffX := part.DiffX
+	diffY := part.DiffY
+	// 反転してから拡大縮小し、中心に寄せる
+	sx := scale
+	tx := 0.0
+	if part.Reverse {
+		sx = -scale
+		tx = w * scale
+		diffX *= -1
+	}
+	tx += -((w - float64(diffX)) * scale) / 2
+	ty := -((h - float64(diffY)) * scale) / 2
+	tx += float64(a.Width / 2)
+	ty += float64(a.Height / 2)
+	return f64.Aff3{
+		sx, 0, tx,
+		0, scale, ty,
+	}
+}
+
+// 拡大縮小と反転をせずに、変換後の中心に元のサイズのまま置いた時のキャンバス内の位置
+// 各種スプライトシート形式のオフセットとして使う
+func (a *Animation) Placement(part *Part) image.Rectangle {
+	m := a.Transform(part)
+	bounds := part.Sprite.Bounds()
+	w, h := float64(bounds.Dx()), float64(bounds.Dy())
+	cx := m[0]*w/2 + m[2]
+	cy := m[4]*h/2 + m[5]
+	x := int(math.Floor(cx - w/2))
+	y := int(math.Floor(cy - h/2))
+	return image.Rect(x, y, x+bounds.Dx(), y+bounds.Dy())
+}
+
+// パーツをebitenを使わずにキャンバスサイズの画像として描画する
+func (a *Animation) RenderFrame(part *Part) *image.RGBA {
+	frame := image.NewRGBA(image.Rect(0, 0, a.Width, a.Height))
+	src := part.Sprite.Source
+	if part.Sprite.IsEmpty() || src == nil {
+		return frame
+	}
+	sr := src.Bounds()
+	// 変換行列は画像の左上を原点としているので、元画像の座標系に合わせる
+	m := a.Transform(part)
+	m[2] -= m[0]*float64(sr.Min.X) + m[1]*float64(sr.Min.Y)
+	m[5] -= m[3]*float64(sr.Min.X) + m[4]*float64(sr.Min.Y)
+	xdraw.NearestNeighbor.Transform(frame, m, src, sr, xdraw.Over, nil)
+	return frame
+}
@@ -0,0 +1,184 @@
+package animation
+
+import (
+	"bytes"
+	"flag"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aethiopicuschan/odori/sprite"
+	"golang.org/x/image/math/f64"
+)
+
+var update = flag.Bool("update", false, "update golden files in testdata")
+
+// 向きが分かるように左上だけ色を変えた4x3のスプライト
+func testSprite() sprite.Sprite {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 3))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{B: 255, A: 255}), image.Point{}, draw.Src)
+	img.Set(0, 0, color.RGBA{R: 255, A: 255})
+	img.Set(3, 2, color.RGBA{G: 255, A: 255})
+	return sprite.NewSpriteWithId(img, "test")
+}
+
+func TestRenderFrame(t *testing.T) {
+	tests := []struct {
+		name   string
+		width  int
+		height int
+		edit   func(part *Part)
+	}{
+		{"plain", 8, 8, func(part *Part) {}},
+		{"scale_up", 12, 10, func(part *Part) { part.Scale = 2 }},
+		{"scale_down", 8, 8, func(part *Part) { part.Scale = 0.5 }},
+		{"diff", 8, 8, func(part *Part) { part.DiffX = 2; part.DiffY = -2 }},
+		{"reverse", 8, 8, func(part *Part) { part.Reverse = true }},
+		{"reverse_diff", 8, 8, func(part *Part) { part.Reverse = true; part.DiffX = 2 }},
+		{"odd_canvas", 7, 5, func(part *Part) {}},
+		{"odd_canvas_scale_reverse", 9, 7, func(part *Part) { part.Scale = 2; part.Reverse = true }},
+		{"empty", 5, 5, func(part *Part) { part.Sprite = sprite.NewEmptySprite() }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewAnimation()
+			a.Width = tt.width
+			a.Height = tt.height
+			part := NewPart(testSprite(), 1)
+			tt.edit(part)
+			frame := a.RenderFrame(part)
+			compareGolden(t, filepath.Join("testdata", "render", tt.name+".png"), frame)
+		})
+	}
+}
+
+// ebiten.GeoMのScaleと同じく、mの後に拡大縮小を適用する
+func geoMScale(m f64.Aff3, x, y float64) f64.Aff3 {
+	return f64.Aff3{
+		m[0] * x, m[1] * x, m[2] * x,
+		m[3] * y, m[4] * y, m[5] * y,
+	}
+}
+
+// ebiten.GeoMのTranslateと同じく、mの後に平行移動を適用する
+func geoMTranslate(m f64.Aff3, x, y float64) f64.Aff3 {
+	m[2] += x
+	m[5] += y
+	return m
+}
+
+func TestTransform(t *testing.T) {
+	tests := []struct {
+		name   string
+		width  int
+		height int
+		edit   func(part *Part)
+	}{
+		{"plain", 8, 8, func(part *Part) {}},
+		{"reverse", 8, 8, func(part *Part) { part.Reverse = true }},
+		{"reverse_diff", 8, 8, func(part *Part) { part.Reverse = true; part.DiffX = 3; part.DiffY = -1 }},
+		{"odd_canvas", 7, 5, func(part *Part) {}},
+		{"odd_canvas_reverse", 9, 7, func(part *Part) { part.Reverse = true; part.DiffX = -2 }},
+		{"fractional_scale", 8, 8, func(part *Part) { part.Scale = 0.75 }},
+		{"fractional_scale_reverse_odd_canvas", 11, 9, func(part *Part) { part.Scale = 1.5; part.Reverse = true; part.DiffX = 1; part.DiffY = 2 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewAnimation()
+			a.Width = tt.width
+			a.Height = tt.height
+			part := NewPart(testSprite(), 1)
+			tt.edit(part)
+
+			// 共通化する前にプレビューで使っていたGeoMの組み立て方
+			w := part.Sprite.Bounds().Dx()
+			h := part.Sprite.Bounds().Dy()
+			diffX := part.DiffX
+			want := f64.Aff3{1, 0, 0, 0, 1, 0}
+			if part.Reverse {
+				want = geoMScale(want, -1, 1)
+				want = geoMTranslate(want, float64(w), 0)
+				diffX *= -1
+			}
+			want = geoMScale(want, part.Scale, part.Scale)
+			want = geoMTranslate(want, -((float64(w-diffX))*part.Scale)/2, -((float64(h-part.DiffY))*part.Scale)/2)
+			want = geoMTranslate(want, float64(a.Width/2), float64(a.Height/2))
+
+			got := a.Transform(part)
+			for i := range got {
+				if math.Abs(got[i]-want[i]) > 1e-9 {
+					t.Fatalf("Transform = %v, want %v", got, want)
+				}
+			}
+		})
+	}
+}
+
+// goldenと画素単位で比較する
+// -updateを付けるとgoldenを書き換える
+func compareGolden(t *testing.T, path string, got *image.RGBA) {
+	t.Helper()
+	if *update {
+		buf := &bytes.Buffer{}
+		if err := png.Encode(buf, got); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	img, err := png.Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := image.NewRGBA(img.Bounds())
+	draw.Draw(want, want.Bounds(), img, img.Bounds().Min, draw.Src)
+	if want.Bounds() != got.Bounds() {
+		t.Fatalf("bounds = %v, want %v", got.Bounds(), want.Bounds())
+	}
+	for y := got.Bounds().Min.Y; y < got.Bounds().Max.Y; y++ {
+		for x := got.Bounds().Min.X; x < got.Bounds().Max.X; x++ {
+			if got.RGBAAt(x, y) != want.RGBAAt(x, y) {
+				t.Fatalf("pixel (%d, %d) = %v, want %v\n%s", x, y, got.RGBAAt(x, y), want.RGBAAt(x, y), dump(got))
+			}
+		}
+	}
+}
+
+// 失敗した時に見比べやすいように1画素1文字で表す
+func dump(img *image.RGBA) string {
+	s := ""
+	for y := img.Bounds().Min.Y; y < img.Bounds().Max.Y; y++ {
+		for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+			c := img.RGBAAt(x, y)
+			switch {
+			case c.A == 0:
+				s += "."
+			case c.R == 255:
+				s += "R"
+			case c.G == 255:
+				s += "G"
+			case c.B == 255:
+				s += "B"
+			default:
+				s += "?"
+			}
+		}
+		s += "\n"
+	}
+	return s
+}
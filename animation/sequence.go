@@ -0,0 +1,169 @@
+package animation
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type SequenceMode string
+
+const (
+	// パーツごとに1枚
+	SequencePerPart SequenceMode = "part"
+	// Tickごとに1枚
+	SequencePerTick SequenceMode = "tick"
+	// 一定のフレームレートごとに1枚
+	SequenceFixedFPS SequenceMode = "fps"
+)
+
+// 連番PNG出力時の設定
+type SequenceOptions struct {
+	Mode SequenceMode
+	// SequenceFixedFPSの時のフレームレート
+	FPS int
+}
+
+func DefaultSequenceOptions() SequenceOptions {
+	return SequenceOptions{
+		Mode: SequencePerPart,
+		FPS:  12,
+	}
+}
+
+// ダイアログで編集するための文字列表現
+func (o SequenceOptions) String() string {
+	return fmt.Sprintf("mode=%s fps=%d", o.Mode, o.FPS)
+}
+
+// "mode=part fps=12"の形式を解釈する
+func ParseSequenceOptions(s string) (o SequenceOptions, err error) {
+	o = DefaultSequenceOptions()
+	for _, field := range strings.Fields(s) {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			err = fmt.Errorf("invalid option: %s", field)
+			return
+		}
+		switch key {
+		case "mode":
+			switch SequenceMode(value) {
+			case SequencePerPart, SequencePerTick, SequenceFixedFPS:
+				o.Mode = SequenceMode(value)
+			default:
+				err = fmt.Errorf("mode must be part, tick or fps: %s", value)
+			}
+		case "fps":
+			o.FPS, err = strconv.Atoi(value)
+			if err == nil && o.FPS < 1 {
+				err = errors.New("fps must be 1 or greater")
+			}
+		default:
+			err = fmt.Errorf("unknown option: %s", key)
+		}
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// 連番PNGと一緒に書き出すマニフェスト
+type SequenceManifest struct {
+	Name   string          `json:"name"`
+	Width  int             `json:"width"`
+	Height int             `json:"height"`
+	Frames []SequenceFrame `json:"frames"`
+}
+
+type SequenceFrame struct {
+	File string `json:"file"`
+	// 元になったパーツの番号
+	Part int `json:"part"`
+	// 表示時間(ミリ秒)
+	Duration int `json:"duration"`
+}
+
+// 合成済みのフレームを"<name>_0001.png"のような連番PNGとして書き出す
+// あわせて"<name>_frames.json"に各フレームの表示時間を書き出す
+func (a *Animation) ExportAsPngSequence(dir, name string, opts SequenceOptions, tps int) (err error) {
+	if !a.CanExport() {
+		return errors.New("can not export")
+	}
+	// ファイルごとのパーツ番号と、1秒あたりrate単位で表した長さ
+	parts := []int{}
+	lengths := []int{}
+	rate := tps
+	switch opts.Mode {
+	case SequencePerPart:
+		for i, part := range a.Parts {
+			parts = append(parts, i)
+			lengths = append(lengths, part.Length)
+		}
+	case SequencePerTick:
+		for i, part := range a.Parts {
+			for t := 0; t < part.Length; t++ {
+				parts = append(parts, i)
+				lengths = append(lengths, 1)
+			}
+		}
+	case SequenceFixedFPS:
+		if opts.FPS < 1 {
+			return errors.New("fps must be 1 or greater")
+		}
+		indexes, maxTick := a.Indexes()
+		for k := 0; k*tps < maxTick*opts.FPS; k++ {
+			parts = append(parts, PartIndexAt(indexes, k*tps/opts.FPS))
+			lengths = append(lengths, 1)
+		}
+		rate = opts.FPS
+	default:
+		return fmt.Errorf("unknown mode: %s", opts.Mode)
+	}
+	durations := ConvertDurations(lengths, rate, 1000, 0)
+
+	manifest := SequenceManifest{
+		Name:   name,
+		Width:  a.Width,
+		Height: a.Height,
+		Frames: []SequenceFrame{},
+	}
+	// 同じパーツは何度も描画しない
+	rendered := make([]*image.RGBA, len(a.Parts))
+	for i, index := range parts {
+		if rendered[index] == nil {
+			rendered[index] = a.RenderFrame(a.Parts[index])
+		}
+		file := fmt.Sprintf("%s_%04d.png", name, i+1)
+		err = writePng(filepath.Join(dir, file), rendered[index])
+		if err != nil {
+			return
+		}
+		manifest.Frames = append(manifest.Frames, SequenceFrame{
+			File:     file,
+			Part:     index,
+			Duration: durations[i],
+		})
+	}
+	bytes, err := MarshalJson(manifest)
+	if err != nil {
+		return
+	}
+	err = os.WriteFile(filepath.Join(dir, name+"_frames.json"), bytes, 0644)
+	return
+}
+
+func writePng(path string, img image.Image) (err error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	err = png.Encode(file, img)
+	return
+}
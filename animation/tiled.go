@@ -0,0 +1,168 @@
+package animation
+
+import (
+	"encoding/xml"
+	"image"
+)
+
+type TiledMode string
+
+const (
+	// スプライトごとにタイルにする
+	TiledCollection TiledMode = "collection"
+	// 合成済みのフレームを等間隔に並べてタイルにする
+	TiledGridMode TiledMode = "grid"
+)
+
+// Tiledのタイルセット(.tsx)
+type TiledTileset struct {
+	XMLName    xml.Name     `xml:"tileset"`
+	Version    string       `xml:"version,attr"`
+	Name       string       `xml:"name,attr"`
+	TileWidth  int          `xml:"tilewidth,attr"`
+	TileHeight int          `xml:"tileheight,attr"`
+	Spacing    int          `xml:"spacing,attr,omitempty"`
+	Margin     int          `xml:"margin,attr,omitempty"`
+	TileCount  int          `xml:"tilecount,attr"`
+	Columns    int          `xml:"columns,attr"`
+	Grid       *TiledGrid   `xml:"grid"`
+	Image      *TiledImage  `xml:"image"`
+	Tiles      []*TiledTile `xml:"tile"`
+}
+
+type TiledGrid struct {
+	Orientation string `xml:"orientation,attr"`
+	Width       int    `xml:"width,attr"`
+	Height      int    `xml:"height,attr"`
+}
+
+type TiledImage struct {
+	Source string `xml:"source,attr"`
+	Width  int    `xml:"width,attr"`
+	Height int    `xml:"height,attr"`
+}
+
+type TiledTile struct {
+	Id int `xml:"id,attr"`
+	// 画像の一部をタイルにする場合の位置(Tiled 1.9以降)
+	X         *int            `xml:"x,attr"`
+	Y         *int            `xml:"y,attr"`
+	Width     *int            `xml:"width,attr"`
+	Height    *int            `xml:"height,attr"`
+	Image     *TiledImage     `xml:"image"`
+	Animation *TiledAnimation `xml:"animation"`
+}
+
+type TiledAnimation struct {
+	Frames []TiledFrame `xml:"frame"`
+}
+
+type TiledFrame struct {
+	TileId int `xml:"tileid,attr"`
+	// 表示時間(ミリ秒)
+	Duration int `xml:"duration,attr"`
+}
+
+const tiledVersion = "1.10"
+
+// スプライトシートの各スプライトをタイルにした画像コレクションのタイルセットを作る
+// アニメーションは最初のパーツのタイルに付ける
+// Tiledではタイルが左下に揃えられるため、拡大縮小・反転・位置の補正があるパーツと空のパーツの番号をinexactPartsとして返す
+func NewTiledCollection(animationP AnimationP, imageName string, imageSize image.Point, tps int) (tileset TiledTileset, inexactParts []int) {
+	a := animationP.Animation
+	tileset = TiledTileset{
+		Version: tiledVersion,
+		Name:    animationP.Name,
+		Grid: &TiledGrid{
+			Orientation: "orthogonal",
+			Width:       1,
+			Height:      1,
+		},
+	}
+	ids := map[string]int{}
+	for _, s := range a.Sprites() {
+		rect, ok := animationP.SpriteSheet[s.Id()]
+		if !ok {
+			continue
+		}
+		ids[s.Id()] = len(tileset.Tiles)
+		x, y, w, h := rect.Min.X, rect.Min.Y, rect.Dx(), rect.Dy()
+		tileset.Tiles = append(tileset.Tiles, &TiledTile{
+			Id:     len(tileset.Tiles),
+			X:      &x,
+			Y:      &y,
+			Width:  &w,
+			Height: &h,
+			Image: &TiledImage{
+				Source: imageName,
+				Width:  imageSize.X,
+				Height: imageSize.Y,
+			},
+		})
+		tileset.TileWidth = max(tileset.TileWidth, w)
+		tileset.TileHeight = max(tileset.TileHeight, h)
+	}
+	tileset.TileCount = len(tileset.Tiles)
+
+	animation := &TiledAnimation{}
+	// 空のパーツは表せないので直前のフレームを延ばす
+	// 直前のフレームがなければ次のフレームに足す
+	isEmpty := func(i int) bool {
+		_, ok := ids[a.Parts[i].Sprite.Id()]
+		return !ok || a.Parts[i].Sprite.IsEmpty()
+	}
+	durations := foldEmptyDurations(a.Durations(tps), isEmpty)
+	for i, part := range a.Parts {
+		if isEmpty(i) {
+			inexactParts = append(inexactParts, i)
+			continue
+		}
+		if part.Scale != 1 || part.Reverse || part.DiffX != 0 || part.DiffY != 0 {
+			inexactParts = append(inexactParts, i)
+		}
+		animation.Frames = append(animation.Frames, TiledFrame{
+			TileId:   ids[part.Sprite.Id()],
+			Duration: durations[i],
+		})
+	}
+	if len(animation.Frames) > 0 {
+		tileset.Tiles[animation.Frames[0].TileId].Animation = animation
+	}
+	return
+}
+
+// BakeSheetで作ったシートを等間隔のタイルとして扱うタイルセットを作る
+// パーツがそのままタイルになり、最初のタイルにアニメーションを付ける
+func NewTiledGrid(animationP AnimationP, imageName string, sheet image.Rectangle, frames []BakedFrame, columns, padding, tps int) (tileset TiledTileset) {
+	a := animationP.Animation
+	tileset = TiledTileset{
+		Version:    tiledVersion,
+		Name:       animationP.Name,
+		TileWidth:  a.Width,
+		TileHeight: a.Height,
+		Spacing:    padding,
+		Margin:     padding,
+		TileCount:  len(frames),
+		Columns:    columns,
+		Image: &TiledImage{
+			Source: imageName,
+			Width:  sheet.Dx(),
+			Height: sheet.Dy(),
+		},
+	}
+	animation := &TiledAnimation{}
+	durations := a.Durations(tps)
+	for i, frame := range frames {
+		animation.Frames = append(animation.Frames, TiledFrame{
+			TileId:   i,
+			Duration: durations[frame.Part],
+		})
+	}
+	tileset.Tiles = []*TiledTile{
+		{
+			Id:        0,
+			Animation: animation,
+		},
+	}
+	return
+}
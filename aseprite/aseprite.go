@@ -0,0 +1,522 @@
+// Asepriteのファイル(.aseprite/.ase)を読み込むためのパッケージ
+// 仕様: https://github.com/aseprite/aseprite/blob/main/docs/ase-file-specs.md
+package aseprite
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"sort"
+)
+
+const (
+	headerMagic = 0xa5e0
+	frameMagic  = 0xf1fa
+)
+
+// チャンクの種類
+const (
+	chunkOldPalette   = 0x0004
+	chunkOldPalette64 = 0x0011
+	chunkLayer        = 0x2004
+	chunkCel          = 0x2005
+	chunkTags         = 0x2018
+	chunkPalette      = 0x2019
+)
+
+// セルの種類
+const (
+	celRaw             = 0
+	celLinked          = 1
+	celCompressedImage = 2
+)
+
+// レイヤーのフラグ
+const (
+	layerVisible    = 1
+	layerBackground = 8
+	layerReference  = 64
+)
+
+const (
+	layerTypeNormal = 0
+)
+
+type Direction int
+
+const (
+	Forward Direction = iota
+	Reverse
+	PingPong
+	PingPongReverse
+)
+
+type File struct {
+	Width  int
+	Height int
+	Frames []Frame
+	Tags   []Tag
+}
+
+type Frame struct {
+	// 表示されているレイヤーを合成した画像
+	Image *image.RGBA
+	// 表示時間(ミリ秒)
+	Duration int
+}
+
+type Tag struct {
+	Name      string
+	From      int
+	To        int
+	Direction Direction
+}
+
+// タグの範囲を再生する順番のフレーム番号を返す
+func (t Tag) FrameIndexes() (indexes []int) {
+	for i := t.From; i <= t.To; i++ {
+		indexes = append(indexes, i)
+	}
+	if t.Direction == Reverse || t.Direction == PingPongReverse {
+		for i, j := 0, len(indexes)-1; i < j; i, j = i+1, j-1 {
+			indexes[i], indexes[j] = indexes[j], indexes[i]
+		}
+	}
+	// 往復する場合は両端を重複させずに折り返す
+	if t.Direction == PingPong || t.Direction == PingPongReverse {
+		for i := len(indexes) - 2; i > 0; i-- {
+			indexes = append(indexes, indexes[i])
+		}
+	}
+	return
+}
+
+type layer struct {
+	visible    bool
+	background bool
+	opacity    uint8
+}
+
+type cel struct {
+	layer   int
+	x, y    int
+	opacity uint8
+	zIndex  int
+	image   *image.NRGBA
+}
+
+type decoder struct {
+	r       *bytes.Reader
+	depth   int
+	transp  uint8
+	palette color.Palette
+	// 新しい形式のパレットを読んだかどうか
+	newPalette bool
+	layers     []layer
+	levels     []bool
+	useOpac    bool
+	cels       [][]*cel
+	durations  []int
+	tags       []Tag
+}
+
+func Decode(r io.Reader) (f *File, err error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return
+	}
+	d := &decoder{
+		r:       bytes.NewReader(data),
+		palette: make(color.Palette, 256),
+	}
+	for i := range d.palette {
+		d.palette[i] = color.NRGBA{}
+	}
+	f = &File{}
+	frames, err := d.readHeader(f)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < frames; i++ {
+		err = d.readFrame(i)
+		if err != nil {
+			return nil, fmt.Errorf("frame %d: %w", i, err)
+		}
+	}
+	for i := range d.cels {
+		f.Frames = append(f.Frames, Frame{
+			Image:    d.flatten(i, f.Width, f.Height),
+			Duration: d.durations[i],
+		})
+	}
+	for _, tag := range d.tags {
+		if tag.From < 0 || tag.To >= frames || tag.From > tag.To {
+			continue
+		}
+		f.Tags = append(f.Tags, tag)
+	}
+	return
+}
+
+func (d *decoder) readHeader(f *File) (frames int, err error) {
+	header := struct {
+		FileSize     uint32
+		Magic        uint16
+		Frames       uint16
+		Width        uint16
+		Height       uint16
+		Depth        uint16
+		Flags        uint32
+		Speed        uint16
+		_            [2]uint32
+		Transparent  uint8
+		_            [3]uint8
+		Colors       uint16
+		PixelWidth   uint8
+		PixelHeight  uint8
+		GridX, GridY int16
+		GridW, GridH uint16
+		_            [84]uint8
+	}{}
+	err = binary.Read(d.r, binary.LittleEndian, &header)
+	if err != nil {
+		return
+	}
+	if header.Magic != headerMagic {
+		err = errors.New("not an aseprite file")
+		return
+	}
+	switch header.Depth {
+	case 32, 16, 8:
+	default:
+		err = fmt.Errorf("unsupported color depth: %d", header.Depth)
+		return
+	}
+	d.depth = int(header.Depth)
+	d.transp = header.Transparent
+	d.useOpac = header.Flags&1 != 0
+	f.Width = int(header.Width)
+	f.Height = int(header.Height)
+	frames = int(header.Frames)
+	return
+}
+
+func (d *decoder) readFrame(index int) (err error) {
+	header := struct {
+		Size      uint32
+		Magic     uint16
+		OldChunks uint16
+		Duration  uint16
+		_         [2]uint8
+		NewChunks uint32
+	}{}
+	err = binary.Read(d.r, binary.LittleEndian, &header)
+	if err != nil {
+		return
+	}
+	if header.Magic != frameMagic {
+		return errors.New("invalid frame magic")
+	}
+	chunks := int(header.NewChunks)
+	if chunks == 0 {
+		chunks = int(header.OldChunks)
+	}
+	d.durations = append(d.durations, int(header.Duration))
+	d.cels = append(d.cels, make([]*cel, len(d.layers)))
+	for i := 0; i < chunks; i++ {
+		var size uint32
+		var kind uint16
+		if err = binary.Read(d.r, binary.LittleEndian, &size); err != nil {
+			return
+		}
+		if err = binary.Read(d.r, binary.LittleEndian, &kind); err != nil {
+			return
+		}
+		if size < 6 || int(size-6) > d.r.Len() {
+			return errors.New("invalid chunk size")
+		}
+		data := make([]byte, size-6)
+		if _, err = io.ReadFull(d.r, data); err != nil {
+			return
+		}
+		c := &chunk{data: data}
+		switch kind {
+		case chunkOldPalette, chunkOldPalette64:
+			if !d.newPalette {
+				d.readOldPalette(c, kind == chunkOldPalette64)
+			}
+		case chunkPalette:
+			d.readPalette(c)
+		case chunkLayer:
+			d.readLayer(c)
+		case chunkCel:
+			err = d.readCel(c, index)
+		case chunkTags:
+			d.readTags(c)
+		}
+		if err == nil {
+			err = c.err
+		}
+		if err != nil {
+			return fmt.Errorf("chunk %#04x: %w", kind, err)
+		}
+	}
+	return
+}
+
+func (d *decoder) readOldPalette(c *chunk, is64 bool) {
+	index := 0
+	packets := int(c.word())
+	for i := 0; i < packets; i++ {
+		index += int(c.byte())
+		n := int(c.byte())
+		if n == 0 {
+			n = 256
+		}
+		for j := 0; j < n; j++ {
+			r, g, b := c.byte(), c.byte(), c.byte()
+			if is64 {
+				r, g, b = r<<2|r>>4, g<<2|g>>4, b<<2|b>>4
+			}
+			if index < len(d.palette) {
+				d.palette[index] = color.NRGBA{r, g, b, 0xff}
+			}
+			index++
+		}
+	}
+}
+
+func (d *decoder) readPalette(c *chunk) {
+	d.newPalette = true
+	size := int(c.dword())
+	first := int(c.dword())
+	last := int(c.dword())
+	c.skip(8)
+	if size > len(d.palette) {
+		palette := make(color.Palette, size)
+		copy(palette, d.palette)
+		for i := len(d.palette); i < size; i++ {
+			palette[i] = color.NRGBA{}
+		}
+		d.palette = palette
+	}
+	for i := first; i <= last && c.err == nil; i++ {
+		flags := c.word()
+		col := color.NRGBA{c.byte(), c.byte(), c.byte(), c.byte()}
+		if flags&1 != 0 {
+			c.string()
+		}
+		if i < len(d.palette) {
+			d.palette[i] = col
+		}
+	}
+}
+
+func (d *decoder) readLayer(c *chunk) {
+	flags := c.word()
+	kind := c.word()
+	level := int(c.word())
+	c.skip(6)
+	opacity := c.byte()
+	if !d.useOpac {
+		opacity = 0xff
+	}
+	// 親のグループが非表示なら子のレイヤーも表示されない
+	visible := flags&layerVisible != 0
+	if level > 0 && level <= len(d.levels) && !d.levels[level-1] {
+		visible = false
+	}
+	if level < len(d.levels) {
+		d.levels = d.levels[:level]
+	}
+	d.levels = append(d.levels, visible)
+	d.layers = append(d.layers, layer{
+		// グループとタイルマップは画素を持たないので合成しない
+		visible:    visible && kind == layerTypeNormal && flags&layerReference == 0,
+		background: flags&layerBackground != 0,
+		opacity:    opacity,
+	})
+}
+
+func (d *decoder) readCel(c *chunk, frame int) (err error) {
+	ce := &cel{
+		layer:   int(c.word()),
+		x:       int(int16(c.word())),
+		y:       int(int16(c.word())),
+		opacity: c.byte(),
+	}
+	kind := c.word()
+	ce.zIndex = int(int16(c.word()))
+	c.skip(5)
+	if ce.layer >= len(d.layers) {
+		return fmt.Errorf("invalid layer index: %d", ce.layer)
+	}
+	switch kind {
+	case celRaw, celCompressedImage:
+		w, h := int(c.word()), int(c.word())
+		pixels := c.rest()
+		if kind == celCompressedImage {
+			var zr io.ReadCloser
+			zr, err = zlib.NewReader(bytes.NewReader(pixels))
+			if err != nil {
+				return
+			}
+			pixels, err = io.ReadAll(zr)
+			zr.Close()
+			if err != nil {
+				return
+			}
+		}
+		ce.image, err = d.decodePixels(pixels, w, h, d.layers[ce.layer].background)
+		if err != nil {
+			return
+		}
+	case celLinked:
+		position := int(c.word())
+		if position >= frame || position >= len(d.cels) || ce.layer >= len(d.cels[position]) || d.cels[position][ce.layer] == nil {
+			return fmt.Errorf("invalid linked cel: %d", position)
+		}
+		linked := *d.cels[position][ce.layer]
+		linked.opacity = ce.opacity
+		linked.zIndex = ce.zIndex
+		linked.x, linked.y = ce.x, ce.y
+		ce = &linked
+	default:
+		// タイルマップなどは読み飛ばす
+		return
+	}
+	cels := d.cels[frame]
+	for len(cels) <= ce.layer {
+		cels = append(cels, nil)
+	}
+	cels[ce.layer] = ce
+	d.cels[frame] = cels
+	return
+}
+
+func (d *decoder) readTags(c *chunk) {
+	n := int(c.word())
+	c.skip(8)
+	for i := 0; i < n && c.err == nil; i++ {
+		tag := Tag{
+			From: int(c.word()),
+			To:   int(c.word()),
+		}
+		tag.Direction = Direction(c.byte())
+		c.skip(2 + 6 + 3 + 1)
+		tag.Name = c.string()
+		d.tags = append(d.tags, tag)
+	}
+}
+
+// 色深度に応じて画素をNRGBAに変換する
+func (d *decoder) decodePixels(pixels []byte, w, h int, background bool) (img *image.NRGBA, err error) {
+	bpp := d.depth / 8
+	if len(pixels) < w*h*bpp {
+		return nil, errors.New("not enough pixel data")
+	}
+	img = image.NewNRGBA(image.Rect(0, 0, w, h))
+	for i := 0; i < w*h; i++ {
+		var col color.NRGBA
+		switch d.depth {
+		case 32:
+			col = color.NRGBA{pixels[i*4], pixels[i*4+1], pixels[i*4+2], pixels[i*4+3]}
+		case 16:
+			v := pixels[i*2]
+			col = color.NRGBA{v, v, v, pixels[i*2+1]}
+		case 8:
+			index := pixels[i]
+			// 背景レイヤー以外では透明色のインデックスは透明になる
+			if index == d.transp && !background {
+				continue
+			}
+			if int(index) < len(d.palette) {
+				col = color.NRGBAModel.Convert(d.palette[index]).(color.NRGBA)
+			}
+		}
+		copy(img.Pix[i*4:i*4+4], []byte{col.R, col.G, col.B, col.A})
+	}
+	return
+}
+
+// フレームの表示されているセルを下のレイヤーから順に重ねる
+func (d *decoder) flatten(frame, w, h int) *image.RGBA {
+	canvas := image.NewRGBA(image.Rect(0, 0, w, h))
+	cels := []*cel{}
+	for _, ce := range d.cels[frame] {
+		if ce != nil && ce.image != nil && d.layers[ce.layer].visible {
+			cels = append(cels, ce)
+		}
+	}
+	// z-indexはレイヤーの順番からのずれを表す
+	sort.SliceStable(cels, func(i, j int) bool {
+		oi, oj := cels[i].layer+cels[i].zIndex, cels[j].layer+cels[j].zIndex
+		if oi != oj {
+			return oi < oj
+		}
+		return cels[i].zIndex < cels[j].zIndex
+	})
+	for _, ce := range cels {
+		opacity := int(ce.opacity) * int(d.layers[ce.layer].opacity) / 0xff
+		mask := image.NewUniform(color.Alpha{uint8(opacity)})
+		rect := ce.image.Bounds().Add(image.Pt(ce.x, ce.y))
+		draw.DrawMask(canvas, rect, ce.image, image.Point{}, mask, image.Point{}, draw.Over)
+	}
+	return canvas
+}
+
+// チャンクのデータを先頭から読む
+// 途中で足りなくなった場合はerrに記録してゼロ値を返す
+type chunk struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (c *chunk) next(n int) []byte {
+	if c.err != nil || c.pos+n > len(c.data) {
+		if c.err == nil {
+			c.err = io.ErrUnexpectedEOF
+		}
+		return make([]byte, n)
+	}
+	b := c.data[c.pos : c.pos+n]
+	c.pos += n
+	return b
+}
+
+func (c *chunk) byte() uint8 {
+	return c.next(1)[0]
+}
+
+func (c *chunk) word() uint16 {
+	return binary.LittleEndian.Uint16(c.next(2))
+}
+
+func (c *chunk) dword() uint32 {
+	return binary.LittleEndian.Uint32(c.next(4))
+}
+
+func (c *chunk) string() string {
+	n := int(c.word())
+	return string(c.next(n))
+}
+
+func (c *chunk) skip(n int) {
+	c.next(n)
+}
+
+func (c *chunk) rest() []byte {
+	if c.err != nil {
+		return nil
+	}
+	b := c.data[c.pos:]
+	c.pos = len(c.data)
+	return b
+}
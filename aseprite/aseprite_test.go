@@ -0,0 +1,192 @@
+package aseprite
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"image/color"
+	"slices"
+	"testing"
+)
+
+// テスト用に.asepriteのバイナリを組み立てる
+func le(values ...any) []byte {
+	buf := &bytes.Buffer{}
+	for _, v := range values {
+		if err := binary.Write(buf, binary.LittleEndian, v); err != nil {
+			panic(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func str(s string) []byte {
+	return append(le(uint16(len(s))), s...)
+}
+
+func chunkBytes(kind uint16, data ...[]byte) []byte {
+	body := bytes.Join(data, nil)
+	return append(le(uint32(len(body)+6), kind), body...)
+}
+
+func layerChunk(flags, kind, level uint16, name string) []byte {
+	return chunkBytes(chunkLayer, le(flags, kind, level, uint16(0), uint16(0), uint16(0), uint8(0xff), [3]uint8{}), str(name))
+}
+
+func celHeader(layer uint16, x, y int16, kind uint16) []byte {
+	return le(layer, x, y, uint8(0xff), kind, int16(0), [5]uint8{})
+}
+
+// 1色で塗りつぶした32bitの画素
+func fill(w, h int, c color.NRGBA) []byte {
+	pixels := []byte{}
+	for i := 0; i < w*h; i++ {
+		pixels = append(pixels, c.R, c.G, c.B, c.A)
+	}
+	return pixels
+}
+
+func rawCel(layer uint16, x, y int16, w, h int, c color.NRGBA) []byte {
+	return chunkBytes(chunkCel, celHeader(layer, x, y, celRaw), le(uint16(w), uint16(h)), fill(w, h, c))
+}
+
+func compressedCel(layer uint16, x, y int16, w, h int, c color.NRGBA) []byte {
+	buf := &bytes.Buffer{}
+	zw := zlib.NewWriter(buf)
+	zw.Write(fill(w, h, c))
+	zw.Close()
+	return chunkBytes(chunkCel, celHeader(layer, x, y, celCompressedImage), le(uint16(w), uint16(h)), buf.Bytes())
+}
+
+func linkedCel(layer uint16, x, y int16, position uint16) []byte {
+	return chunkBytes(chunkCel, celHeader(layer, x, y, celLinked), le(position))
+}
+
+func tagsChunk(tags ...Tag) []byte {
+	data := [][]byte{le(uint16(len(tags)), [8]uint8{})}
+	for _, tag := range tags {
+		data = append(data, le(uint16(tag.From), uint16(tag.To), uint8(tag.Direction), uint16(0), [6]uint8{}, [3]uint8{}, uint8(0)), str(tag.Name))
+	}
+	return chunkBytes(chunkTags, data...)
+}
+
+func frameBytes(duration uint16, chunks ...[]byte) []byte {
+	body := bytes.Join(chunks, nil)
+	return append(le(uint32(len(body)+16), uint16(frameMagic), uint16(len(chunks)), duration, [2]uint8{}, uint32(len(chunks))), body...)
+}
+
+func fileBytes(w, h int, frames ...[]byte) []byte {
+	body := bytes.Join(frames, nil)
+	header := le(
+		uint32(len(body)+128), uint16(headerMagic), uint16(len(frames)), uint16(w), uint16(h), uint16(32),
+		uint32(1), uint16(100), [2]uint32{}, uint8(0), [3]uint8{}, uint16(0), uint8(1), uint8(1),
+		int16(0), int16(0), uint16(16), uint16(16), [84]uint8{},
+	)
+	return append(header, body...)
+}
+
+var (
+	red   = color.NRGBA{R: 255, A: 255}
+	green = color.NRGBA{G: 255, A: 255}
+	blue  = color.NRGBA{B: 255, A: 255}
+)
+
+func TestDecode(t *testing.T) {
+	data := fileBytes(2, 2,
+		frameBytes(100,
+			layerChunk(layerVisible, layerTypeNormal, 0, "base"),
+			// 非表示のグループの中のレイヤーは表示されていても合成しない
+			layerChunk(0, 1, 0, "hidden"),
+			layerChunk(layerVisible, layerTypeNormal, 1, "child"),
+			layerChunk(layerVisible, layerTypeNormal, 0, "top"),
+			compressedCel(0, 0, 0, 2, 2, red),
+			rawCel(2, 0, 0, 2, 2, blue),
+			rawCel(3, 1, 1, 1, 1, green),
+			tagsChunk(
+				Tag{Name: "walk", From: 0, To: 2, Direction: PingPong},
+				Tag{Name: "out_of_range", From: 1, To: 3, Direction: Forward},
+			),
+		),
+		frameBytes(50,
+			linkedCel(0, 0, 0, 0),
+		),
+		frameBytes(200,
+			linkedCel(0, 0, 0, 0),
+			rawCel(3, 0, 0, 1, 1, green),
+		),
+	)
+	f, err := Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Width != 2 || f.Height != 2 {
+		t.Fatalf("size = %dx%d, want 2x2", f.Width, f.Height)
+	}
+	durations := []int{}
+	for _, frame := range f.Frames {
+		durations = append(durations, frame.Duration)
+	}
+	if !slices.Equal(durations, []int{100, 50, 200}) {
+		t.Fatalf("durations = %v, want [100 50 200]", durations)
+	}
+
+	tests := []struct {
+		frame int
+		x, y  int
+		want  color.NRGBA
+	}{
+		{0, 0, 0, red},
+		{0, 1, 0, red},
+		{0, 1, 1, green},
+		{1, 0, 0, red},
+		{1, 1, 1, red},
+		{2, 0, 0, green},
+		{2, 1, 1, red},
+	}
+	for _, tt := range tests {
+		got := color.NRGBAModel.Convert(f.Frames[tt.frame].Image.At(tt.x, tt.y)).(color.NRGBA)
+		if got != tt.want {
+			t.Errorf("frame %d (%d, %d) = %v, want %v", tt.frame, tt.x, tt.y, got, tt.want)
+		}
+	}
+
+	// 範囲外のタグは読み飛ばす
+	if len(f.Tags) != 1 {
+		t.Fatalf("tags = %v, want 1 tag", f.Tags)
+	}
+	if want := (Tag{Name: "walk", From: 0, To: 2, Direction: PingPong}); f.Tags[0] != want {
+		t.Fatalf("tag = %v, want %v", f.Tags[0], want)
+	}
+}
+
+func TestDecodeInvalidLinkedCel(t *testing.T) {
+	data := fileBytes(1, 1,
+		frameBytes(100,
+			layerChunk(layerVisible, layerTypeNormal, 0, "base"),
+			linkedCel(0, 0, 0, 0),
+		),
+	)
+	if _, err := Decode(bytes.NewReader(data)); err == nil {
+		t.Fatal("Decode succeeded with a cel linked to its own frame")
+	}
+}
+
+func TestFrameIndexes(t *testing.T) {
+	tests := []struct {
+		tag  Tag
+		want []int
+	}{
+		{Tag{From: 1, To: 3, Direction: Forward}, []int{1, 2, 3}},
+		{Tag{From: 1, To: 3, Direction: Reverse}, []int{3, 2, 1}},
+		{Tag{From: 1, To: 4, Direction: PingPong}, []int{1, 2, 3, 4, 3, 2}},
+		{Tag{From: 1, To: 4, Direction: PingPongReverse}, []int{4, 3, 2, 1, 2, 3}},
+		{Tag{From: 0, To: 1, Direction: PingPong}, []int{0, 1}},
+		{Tag{From: 2, To: 2, Direction: PingPong}, []int{2}},
+	}
+	for _, tt := range tests {
+		got := tt.tag.FrameIndexes()
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%+v.FrameIndexes() = %v, want %v", tt.tag, got, tt.want)
+		}
+	}
+}
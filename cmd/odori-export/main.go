@@ -0,0 +1,13 @@
+// ディスプレイのない環境でodori exportと同じ変換を行うコマンド
+// エディタとebitenをリンクしないので、CIなどでもそのまま動く
+package main
+
+import (
+	"os"
+
+	"github.com/aethiopicuschan/odori/command"
+)
+
+func main() {
+	os.Exit(command.Export(os.Args[1:]))
+}
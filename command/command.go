@@ -0,0 +1,297 @@
+// エディタを開かずにプロジェクトを変換するサブコマンド
+// ウィンドウのない環境でも動くように、このパッケージはebitenに依存しない
+package command
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aethiopicuschan/odori/animation"
+	"github.com/aethiopicuschan/odori/constant"
+	"github.com/aethiopicuschan/odori/io"
+)
+
+const usage = `Usage:
+  odori                                    Open the editor
+  odori export [options] <project>         Export a project (.json or .odori) without opening a window
+  odori-export [options] <project>         Same as odori export, but runs without a display
+
+Options of export:
+`
+
+// サブコマンドを実行して終了コードを返す
+func Run(args []string) int {
+	switch args[0] {
+	case "export":
+		return Export(args[1:])
+	case "help", "-h", "-help", "--help":
+		fs, _ := newExportFlagSet()
+		printUsage(fs)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "odori: unknown command %q\n", args[0])
+		fs, _ := newExportFlagSet()
+		printUsage(fs)
+		return 2
+	}
+}
+
+// exportサブコマンドを実行して終了コードを返す
+func Export(args []string) int {
+	if err := runExport(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintf(os.Stderr, "odori: %s\n", err.Error())
+		return 1
+	}
+	return 0
+}
+
+type exportFlags struct {
+	format   string
+	output   string
+	sequence string
+	fps      int
+	columns  int
+	padding  int
+	order    string
+	tiled    string
+	pkg      string
+	loop     int
+	tps      int
+}
+
+func newExportFlagSet() (*flag.FlagSet, *exportFlags) {
+	f := &exportFlags{}
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.StringVar(&f.format, "format", "gif", "output format (gif, apng, png-seq, sheet, baked-sheet, aseprite, godot, tiled, phaser, html, go, odori)")
+	fs.StringVar(&f.output, "o", "", "output file for gif, apng, aseprite, godot, tiled, phaser, html and odori, output directory for png-seq, sheet, baked-sheet and go, created if missing (default: current directory)")
+	fs.StringVar(&f.sequence, "sequence", "part", "how png-seq splits frames (part, tick, fps)")
+	fs.IntVar(&f.fps, "fps", 12, "frame rate of png-seq when -sequence is fps")
+	fs.IntVar(&f.columns, "columns", 0, "frames per row of baked-sheet (0 puts all frames in a row)")
+	fs.IntVar(&f.padding, "padding", 0, "pixels around each frame of baked-sheet")
+	fs.StringVar(&f.order, "order", "row", "order of frames in baked-sheet (row, column)")
+	fs.StringVar(&f.tiled, "tiled", "collection", "how tiled makes tiles (collection of sprites, grid of rendered frames)")
+	fs.StringVar(&f.pkg, "package", "anims", "package name of go")
+	fs.IntVar(&f.tps, "tps", constant.DefaultTPS, "ticks per second that part lengths are based on")
+	fs.IntVar(&f.loop, "loop", 0, "loop count of apng (0 loops forever, -1 plays once)")
+	return fs, f
+}
+
+func printUsage(fs *flag.FlagSet) {
+	fmt.Fprint(os.Stderr, usage)
+	fs.SetOutput(os.Stderr)
+	fs.PrintDefaults()
+}
+
+func runExport(args []string) (err error) {
+	fs, f := newExportFlagSet()
+	fs.Usage = func() {
+		printUsage(fs)
+	}
+	err = fs.Parse(args)
+	if err != nil {
+		return
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("export requires exactly one project")
+	}
+
+	readCh := make(chan io.ReadProjectResult)
+	if strings.ToLower(filepath.Ext(fs.Arg(0))) == animation.BundleExt {
+		go io.ReadBundle(readCh, fs.Arg(0))
+	} else {
+		go io.ReadProject(readCh, fs.Arg(0))
+	}
+	readResult := <-readCh
+	close(readCh)
+	if readResult.Err != nil {
+		return readResult.Err
+	}
+	animationP := readResult.AnimationP
+	if f.tps < 1 {
+		return errors.New("tps must be 1 or greater")
+	}
+	tps := f.tps
+
+	switch f.format {
+	case "gif":
+		var output string
+		output, err = outputFile(f.output, animationP.Name+".gif")
+		if err != nil {
+			return
+		}
+		opts := animation.DefaultGifOptions()
+		if animationP.Animation.GifOptions != nil {
+			opts = *animationP.Animation.GifOptions
+		}
+		var shortFrames []int
+		shortFrames, err = animationP.Animation.ExportAsGif(output, opts, tps)
+		if len(shortFrames) > 0 {
+			fmt.Fprintf(os.Stderr, "odori: warning: %d frames are shorter than %d/100 sec and may be played slower in browsers\n", len(shortFrames), animation.MinimumGifDelay)
+		}
+	case "apng":
+		if f.loop < -1 {
+			return errors.New("loop must be -1 or greater")
+		}
+		var output string
+		output, err = outputFile(f.output, animationP.Name+"_apng.png")
+		if err != nil {
+			return
+		}
+		opts := animation.ApngOptions{
+			LoopCount: f.loop,
+		}
+		err = animationP.Animation.ExportAsApng(output, opts, tps)
+	case "png-seq":
+		var dir string
+		dir, err = outputDir(f.output)
+		if err != nil {
+			return
+		}
+		opts := animation.SequenceOptions{
+			Mode: animation.SequenceMode(f.sequence),
+			FPS:  f.fps,
+		}
+		err = animationP.Animation.ExportAsPngSequence(dir, animationP.Name, opts, tps)
+	case "sheet":
+		var dir string
+		dir, err = outputDir(f.output)
+		if err != nil {
+			return
+		}
+		writeCh := make(chan error)
+		go io.WriteProject(writeCh, dir, animationP.Name, animationP.Animation, readResult.Sprites)
+		err = <-writeCh
+		close(writeCh)
+	case "baked-sheet":
+		var dir string
+		dir, err = outputDir(f.output)
+		if err != nil {
+			return
+		}
+		opts := animation.BakedOptions{
+			Columns: f.columns,
+			Padding: f.padding,
+			Order:   animation.BakedOrder(f.order),
+		}
+		err = animationP.Animation.ExportAsBakedSheet(dir, animationP.Name, opts, tps)
+	case "aseprite":
+		var output string
+		output, err = outputFile(f.output, animationP.Name+"_aseprite.json")
+		if err != nil {
+			return
+		}
+		writeCh := make(chan io.WriteAsepriteResult)
+		go io.WriteAseprite(writeCh, output, animationP, tps)
+		writeResult := <-writeCh
+		close(writeCh)
+		err = writeResult.Err
+		if len(writeResult.InexactParts) > 0 {
+			fmt.Fprintf(os.Stderr, "odori: warning: %d parts are scaled or reversed, which Aseprite JSON can not express\n", len(writeResult.InexactParts))
+		}
+	case "godot":
+		var output string
+		output, err = outputFile(f.output, animationP.Name+"_godot.tres")
+		if err != nil {
+			return
+		}
+		writeCh := make(chan io.WriteGodotResult)
+		go io.WriteGodot(writeCh, output, animationP, tps)
+		writeResult := <-writeCh
+		close(writeCh)
+		err = writeResult.Err
+		if len(writeResult.InexactParts) > 0 {
+			fmt.Fprintf(os.Stderr, "odori: warning: %d parts are scaled, reversed or moved, which Godot SpriteFrames can not express\n", len(writeResult.InexactParts))
+		}
+	case "tiled":
+		var output string
+		output, err = outputFile(f.output, animationP.Name+"_tiled.tsx")
+		if err != nil {
+			return
+		}
+		writeCh := make(chan io.WriteTiledResult)
+		go io.WriteTiled(writeCh, output, animationP, animation.TiledMode(f.tiled), tps)
+		writeResult := <-writeCh
+		close(writeCh)
+		err = writeResult.Err
+		if len(writeResult.InexactParts) > 0 {
+			fmt.Fprintf(os.Stderr, "odori: warning: %d parts are empty, scaled, reversed or moved, which Tiled tiles can not express\n", len(writeResult.InexactParts))
+		}
+	case "phaser":
+		var output string
+		output, err = outputFile(f.output, animationP.Name+"_atlas.json")
+		if err != nil {
+			return
+		}
+		writeCh := make(chan io.WritePhaserResult)
+		go io.WritePhaser(writeCh, output, animationP, tps)
+		writeResult := <-writeCh
+		close(writeCh)
+		err = writeResult.Err
+		if len(writeResult.InexactParts) > 0 {
+			fmt.Fprintf(os.Stderr, "odori: warning: %d parts are empty, scaled or reversed, which Phaser atlas can not express\n", len(writeResult.InexactParts))
+		}
+	case "html":
+		var output string
+		output, err = outputFile(f.output, animationP.Name+".html")
+		if err != nil {
+			return
+		}
+		writeCh := make(chan error)
+		go io.WriteHtml(writeCh, output, animationP, tps)
+		err = <-writeCh
+		close(writeCh)
+	case "go":
+		var dir string
+		dir, err = outputDir(f.output)
+		if err != nil {
+			return
+		}
+		writeCh := make(chan error)
+		go io.WriteGoSource(writeCh, dir, f.pkg, animationP, tps)
+		err = <-writeCh
+		close(writeCh)
+	case "odori":
+		var output string
+		output, err = outputFile(f.output, animationP.Name+animation.BundleExt)
+		if err != nil {
+			return
+		}
+		writeCh := make(chan error)
+		go io.WriteBundle(writeCh, output, animationP.Name, animationP.Animation, readResult.Sprites)
+		err = <-writeCh
+		close(writeCh)
+	default:
+		err = fmt.Errorf("unknown format %q", f.format)
+	}
+	return
+}
+
+// 出力先のファイルのパスを返す
+// pathが空の場合はdefaultPathにし、親のディレクトリがなければ作る
+func outputFile(path, defaultPath string) (file string, err error) {
+	file = path
+	if file == "" {
+		file = defaultPath
+	}
+	err = os.MkdirAll(filepath.Dir(file), 0755)
+	return
+}
+
+// 出力先のディレクトリがなければ作る
+func outputDir(path string) (dir string, err error) {
+	dir = path
+	if dir == "" {
+		dir = "."
+	}
+	dir = filepath.Clean(dir)
+	err = os.MkdirAll(dir, 0755)
+	return
+}
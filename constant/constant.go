@@ -20,4 +20,9 @@ const (
 	DisabledGrayY         = 128
 	WindowTitle           = "Odori"
 	OdoriVersion          = "0.0.0"
+	// 書き出すJSONの形式のバージョン
+	FormatVersion = 2
+	// ebitenのデフォルトのTPSと同じ
+	// ウィンドウを開かないエクスポートではebiten.TPS()が使えないのでこれを使う
+	DefaultTPS = 60
 )
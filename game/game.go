@@ -1,18 +1,19 @@
 package game
 
 import (
-	"encoding/json"
 	"fmt"
-	"image"
 	"image/color"
 	"path/filepath"
+	"strings"
 
 	"github.com/aethiopicuschan/odori/animation"
+	"github.com/aethiopicuschan/odori/aseprite"
 	"github.com/aethiopicuschan/odori/constant"
 	"github.com/aethiopicuschan/odori/io"
 	"github.com/aethiopicuschan/odori/sprite"
 	"github.com/aethiopicuschan/odori/ui"
 	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
 )
 
 type Game struct {
@@ -24,6 +25,10 @@ type Game struct {
 	explorer   *ui.Explorer
 	player     *ui.Player
 	name       string
+	// Saveで上書きする場所(.odoriか、Exportした<name>.json)
+	path string
+	// タイトルに未保存の印を表示しているか
+	titleDirty bool
 }
 
 func NewGame() ebiten.Game {
@@ -34,16 +39,22 @@ func NewGame() ebiten.Game {
 	buttonHeight := 30
 	buttonMap := map[string]func(){}
 	buttonMap["New animation"] = game.newAnimation
+	buttonMap["Open"] = game.openBundle
+	buttonMap["Save"] = game.save
+	buttonMap["Save as..."] = game.saveAs
 	buttonMap["Load files"] = game.loadFiles
 	buttonMap["Load sprite sheet"] = game.loadSpriteSheet
 	buttonMap["Import"] = game.importAnimation
 	buttonMap["Export"] = game.exportAnimation
-	buttonMap["Export as GIF"] = game.exportAsGif
+	buttonMap["Export as..."] = game.exportAs
 	buttonList := []string{
 		"New animation",
+		"Open",
+		"Save",
+		"Save as...",
 		"Import",
 		"Export",
-		"Export as GIF",
+		"Export as...",
 		"Load files",
 		"Load sprite sheet",
 	}
@@ -65,19 +76,32 @@ func NewGame() ebiten.Game {
 }
 
 func (g *Game) Update() error {
-	// TODO 開いているプロジェクトがあるときにWindowを閉じるときは確認する
+	if ebiten.IsWindowBeingClosed() && g.confirmClose() {
+		return ebiten.Termination
+	}
+	if g.player != nil && g.player.IsDirty() != g.titleDirty {
+		g.updateWindowTitle()
+	}
 	ebiten.SetCursorShape(ebiten.CursorShapeDefault)
 	for _, button := range g.buttons {
 		if g.name == "" {
-			button.SetDisabled(button.Label() != "New animation" && button.Label() != "Import")
+			button.SetDisabled(button.Label() != "New animation" && button.Label() != "Open" && button.Label() != "Import")
 		} else {
-			if button.Label() == "Export" || button.Label() == "Export as GIF" {
+			if button.Label() == "Export" || button.Label() == "Export as..." {
 				button.SetDisabled(!g.player.RawAnimation().CanExport())
 			} else {
-				button.SetDisabled(button.Label() == "New animation" || button.Label() == "Import")
+				button.SetDisabled(button.Label() == "New animation" || button.Label() == "Open" || button.Label() == "Import")
 			}
 		}
 	}
+	// Ctrl+S(macOSではCmd+S)で保存し、Shiftも押されていれば場所を選び直す
+	if g.name != "" && ebiten.IsFocused() && inpututil.IsKeyJustPressed(ebiten.KeyS) && (ebiten.IsKeyPressed(ebiten.KeyControl) || ebiten.IsKeyPressed(ebiten.KeyMeta)) {
+		if ebiten.IsKeyPressed(ebiten.KeyShift) {
+			g.saveAs()
+		} else {
+			g.save()
+		}
+	}
 	for _, c := range g.components {
 		c.Update()
 	}
@@ -110,7 +134,8 @@ func (g *Game) startProject(name string) {
 		return
 	}
 	g.name = name
-	ebiten.SetWindowTitle(constant.WindowTitle + " - " + g.name)
+	g.path = ""
+	g.updateWindowTitle()
 	for i, component := range g.components {
 		// noticerを一度消す
 		if component == g.noticer {
@@ -131,6 +156,20 @@ func (g *Game) startProject(name string) {
 	g.components = append(g.components, g.noticer)
 }
 
+// プロジェクト名と保存先をタイトルに表示する
+// 保存していない変更があれば名前の後ろに*を付ける
+func (g *Game) updateWindowTitle() {
+	title := constant.WindowTitle + " - " + g.name
+	g.titleDirty = g.player != nil && g.player.IsDirty()
+	if g.titleDirty {
+		title += "*"
+	}
+	if g.path != "" {
+		title += " (" + g.path + ")"
+	}
+	ebiten.SetWindowTitle(title)
+}
+
 func (g *Game) newAnimation() {
 	if g.name != "" {
 		return
@@ -178,6 +217,8 @@ func (g *Game) loadFiles() {
 		if appended == 0 {
 			g.noticer.AddNotice(ui.WARN, "No sprite is loaded!")
 		} else {
+			// 読み込んだスプライトもプロジェクトに保存される
+			g.player.MarkDirty()
 			g.noticer.AddNotice(ui.INFO, fmt.Sprintf("%d sprites are loaded!", appended))
 		}
 	}()
@@ -186,7 +227,8 @@ func (g *Game) loadFiles() {
 func (g *Game) loadSpriteSheet() {
 	go func() {
 		pickCh := make(chan io.PickResult)
-		go io.Pick(pickCh, io.WithName("Select sprite sheet"), io.WithPatterns([]string{"*.png"}))
+		// PNGはKabanで自動検出し、JSONはTexturePacker形式のアトラスとして読み込む
+		go io.Pick(pickCh, io.WithName("Select sprite sheet"), io.WithPatterns([]string{"*.png", "*.json"}))
 		result := <-pickCh
 		close(pickCh)
 		if result.Err != nil {
@@ -196,7 +238,11 @@ func (g *Game) loadSpriteSheet() {
 			return
 		}
 		chRead := make(chan io.ReadSpriteSheetResult)
-		go io.ReadSpriteSheet(chRead, result.Path)
+		if strings.ToLower(filepath.Ext(result.Path)) == ".json" {
+			go io.ReadAtlas(chRead, result.Path)
+		} else {
+			go io.ReadSpriteSheet(chRead, result.Path)
+		}
 		readResult := <-chRead
 		close(chRead)
 		if readResult.Err != nil {
@@ -210,6 +256,7 @@ func (g *Game) loadSpriteSheet() {
 		if appended == 0 {
 			g.noticer.AddNotice(ui.WARN, "No sprite is loaded!")
 		} else {
+			g.player.MarkDirty()
 			g.noticer.AddNotice(ui.INFO, fmt.Sprintf("%d sprites are loaded!", appended))
 		}
 	}()
@@ -238,8 +285,7 @@ func (g *Game) changeAnimationSize() {
 			g.noticer.AddNotice(ui.ERROR, "Invalid size!")
 			return
 		}
-		raw.Width = animationWidth
-		raw.Height = animationHeight
+		g.player.Resize(animationWidth, animationHeight)
 		g.noticer.AddNotice(ui.INFO, fmt.Sprintf("Animation size is changed to %dx%d", animationWidth, animationHeight))
 	}()
 }
@@ -262,7 +308,7 @@ func (g *Game) renameAnimation() {
 		}
 		g.name = result.Input
 		g.player.Rename(g.name)
-		ebiten.SetWindowTitle(constant.WindowTitle + " - " + g.name)
+		g.updateWindowTitle()
 		g.noticer.AddNotice(ui.INFO, fmt.Sprintf(`Animation name is changed to "%s"`, g.name))
 	}()
 }
@@ -273,16 +319,6 @@ func (g *Game) exportAnimation() {
 	}
 	g.player.Stop()
 	raw := g.player.RawAnimation()
-	m := map[string]sprite.Sprite{}
-	for _, part := range raw.Parts {
-		if !part.Sprite.IsEmpty() {
-			m[part.Sprite.Id()] = part.Sprite
-		}
-	}
-	sprites := []sprite.Sprite{}
-	for _, sprite := range m {
-		sprites = append(sprites, sprite)
-	}
 	selectDirCh := make(chan io.SelectDirResult)
 	go io.SelectDir(selectDirCh)
 	result := <-selectDirCh
@@ -305,44 +341,24 @@ func (g *Game) exportAnimation() {
 			return
 		}
 	}
-	spriteSheet := map[string]image.Rectangle{}
-	// スプライトシートの出力
-	if len(sprites) != 0 {
-		ch := make(chan io.WriteSpriteSheetResult)
-		go io.WriteSpriteSheet(ch, sprites, filepath.Join(dir, g.name+".png"))
-		result := <-ch
-		close(ch)
-		if result.Err != nil {
-			g.noticer.AddNotice(ui.ERROR, result.Err.Error())
-			return
-		}
-		spriteSheet = result.RectsMap
-	}
-	// AnimationのJSON出力
-	bytes, err := json.MarshalIndent(animation.AnimationP{
-		Name:        g.name,
-		Animation:   raw,
-		SpriteSheet: spriteSheet,
-	}, "", "  ")
-	if err != nil {
-		g.noticer.AddNotice(ui.ERROR, err.Error())
-		return
-	}
 	writeCh := make(chan error)
-	go io.Write(writeCh, bytes, filepath.Join(dir, g.name+".json"))
-	err = <-writeCh
+	go io.WriteProject(writeCh, dir, g.name, raw, g.explorer.Sprites())
+	err := <-writeCh
 	close(writeCh)
 	if err != nil {
 		g.noticer.AddNotice(ui.ERROR, err.Error())
 		return
 	}
+	g.path = jsonPath
+	g.player.MarkSaved()
+	g.updateWindowTitle()
 	g.noticer.AddNotice(ui.INFO, "Exported!")
 }
 
 func (g *Game) importAnimation() {
-	// JSONを読み込ませる
+	// JSONかGIFかAsepriteかPiskelのファイルを読み込ませる
 	pickCh := make(chan io.PickResult)
-	go io.Pick(pickCh, io.WithName("Select animation"), io.WithPatterns([]string{"*.json"}))
+	go io.Pick(pickCh, io.WithName("Select animation"), io.WithPatterns([]string{"*.json", "*.gif", "*.aseprite", "*.ase", "*.piskel"}))
 	result := <-pickCh
 	close(pickCh)
 	if result.Err != nil {
@@ -351,56 +367,274 @@ func (g *Game) importAnimation() {
 		}
 		return
 	}
-	// JSONの読み込み
-	readCh := make(chan io.ReadResult)
-	go io.Read(readCh, result.Path)
+	switch strings.ToLower(filepath.Ext(result.Path)) {
+	case ".gif":
+		g.importGif(result.Path)
+		return
+	case ".aseprite", ".ase":
+		g.importAseprite(result.Path)
+		return
+	case ".piskel":
+		g.importPiskel(result.Path)
+		return
+	}
+	// JSONとスプライトシートの読み込み
+	readCh := make(chan io.ReadProjectResult)
+	go io.ReadProject(readCh, result.Path)
 	readResult := <-readCh
 	close(readCh)
 	if readResult.Err != nil {
 		g.noticer.AddNotice(ui.ERROR, fmt.Sprintf("%s: %s", readResult.Err.Error(), result.Path))
 		return
 	}
-	var animationP animation.AnimationP
-	err := json.Unmarshal(readResult.Bytes, &animationP)
+	// Saveでは"<name>.json"に書き出すので、ファイル名が違っていても合わせておく
+	g.loadProject(readResult, filepath.Join(filepath.Dir(result.Path), readResult.AnimationP.Name+".json"))
+}
+
+// 読み込んだプロジェクトを開く
+// pathは次にSaveで上書きする場所
+func (g *Game) loadProject(readResult io.ReadProjectResult, path string) {
+	animationP := readResult.AnimationP
+	g.startProject(animationP.Name)
+	g.path = path
+	g.updateWindowTitle()
+	if len(readResult.Sprites) > 0 {
+		for _, sprite := range readResult.Sprites {
+			g.explorer.AppendSprite(sprite)
+		}
+		g.player.Import(animationP.Animation)
+		g.noticer.AddNotice(ui.INFO, fmt.Sprintf(`Project "%s" was imported with %d sprites!`, animationP.Name, len(readResult.Sprites)))
+	} else {
+		g.player.Import(animationP.Animation)
+		g.noticer.AddNotice(ui.INFO, fmt.Sprintf(`Project "%s" was imported!`, animationP.Name))
+	}
+}
+
+func (g *Game) openBundle() {
+	if g.name != "" {
+		return
+	}
+	pickCh := make(chan io.PickResult)
+	go io.Pick(pickCh, io.WithName("Open project"), io.WithPatterns([]string{"*" + animation.BundleExt}))
+	result := <-pickCh
+	close(pickCh)
+	if result.Err != nil {
+		if result.Err.Error() != "dialog canceled" {
+			g.noticer.AddNotice(ui.WARN, result.Err.Error())
+		}
+		return
+	}
+	readCh := make(chan io.ReadProjectResult)
+	go io.ReadBundle(readCh, result.Path)
+	readResult := <-readCh
+	close(readCh)
+	if readResult.Err != nil {
+		g.noticer.AddNotice(ui.ERROR, fmt.Sprintf("%s: %s", readResult.Err.Error(), result.Path))
+		return
+	}
+	g.loadProject(readResult, result.Path)
+}
+
+// 前回の場所に確認なしで上書きする
+// まだ一度も保存していなければSave as...と同じ
+func (g *Game) save() {
+	if g.name == "" {
+		return
+	}
+	if g.path == "" {
+		g.saveAs()
+		return
+	}
+	g.player.Stop()
+	g.saveTo(g.path)
+}
+
+func (g *Game) saveAs() {
+	if g.name == "" {
+		return
+	}
+	g.player.Stop()
+	pickCh := make(chan io.PickResult)
+	go io.Pick(pickCh, io.WithName("Save project"), io.WithPatterns([]string{"*" + animation.BundleExt}), io.WithToSave(g.name+animation.BundleExt))
+	result := <-pickCh
+	close(pickCh)
+	if result.Err != nil {
+		if result.Err.Error() != "dialog canceled" {
+			g.noticer.AddNotice(ui.WARN, result.Err.Error())
+		}
+		return
+	}
+	path := result.Path
+	if filepath.Ext(path) == "" {
+		path += animation.BundleExt
+	}
+	g.saveTo(path)
+}
+
+// pathの拡張子に合わせて書き出し、次のSaveの場所にする
+func (g *Game) saveTo(path string) {
+	writeCh := make(chan error)
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		// Exportと同じ形式で、名前が変わっていれば"<name>.json"に書き出す
+		dir := filepath.Dir(path)
+		path = filepath.Join(dir, g.name+".json")
+		go io.WriteProject(writeCh, dir, g.name, g.player.RawAnimation(), g.explorer.Sprites())
+	} else {
+		go io.WriteBundle(writeCh, path, g.name, g.player.RawAnimation(), g.explorer.Sprites())
+	}
+	err := <-writeCh
+	close(writeCh)
 	if err != nil {
 		g.noticer.AddNotice(ui.ERROR, err.Error())
 		return
 	}
-	withSpriteSheet := false
-	for _, part := range animationP.Animation.Parts {
-		if !part.Sprite.IsEmpty() {
-			withSpriteSheet = true
-			break
-		}
+	g.path = path
+	g.player.MarkSaved()
+	g.updateWindowTitle()
+	g.noticer.AddNotice(ui.INFO, "Saved!")
+}
+
+// ウィンドウを閉じてよいか
+// 保存していない変更があれば保存するか破棄するか聞く
+func (g *Game) confirmClose() bool {
+	if g.player == nil || !g.player.IsDirty() {
+		return true
 	}
-	// スプライトシートの読み込み
-	if withSpriteSheet {
-		si, err := io.ReadPng(filepath.Join(filepath.Dir(result.Path), animationP.Name+".png"))
-		if err != nil {
-			g.noticer.AddNotice(ui.ERROR, err.Error())
-			return
+	g.player.Stop()
+	questionCh := make(chan io.QuestionWithExtraResult)
+	go io.QuestionWithExtra(questionCh, "Unsaved changes", fmt.Sprintf(`Save changes to "%s" before closing?`, g.name), "Save", "Discard", "Cancel")
+	result := <-questionCh
+	close(questionCh)
+	if result.Extra {
+		return true
+	}
+	if !result.Answer {
+		return false
+	}
+	g.save()
+	// 保存に失敗したりキャンセルされたりしたら閉じない
+	return !g.player.IsDirty()
+}
+
+func (g *Game) importGif(path string) {
+	readCh := make(chan io.ReadGifResult)
+	go io.ReadGif(readCh, path, ebiten.TPS())
+	readResult := <-readCh
+	close(readCh)
+	if readResult.Err != nil {
+		g.noticer.AddNotice(ui.ERROR, fmt.Sprintf("%s: %s", readResult.Err.Error(), path))
+		return
+	}
+	g.startProject(readResult.Name)
+	for _, sprite := range readResult.Sprites {
+		g.explorer.AppendSprite(sprite)
+	}
+	g.player.Import(readResult.Animation)
+	g.noticer.AddNotice(ui.INFO, fmt.Sprintf(`Project "%s" was imported from GIF with %d sprites!`, readResult.Name, len(readResult.Sprites)))
+}
+
+func (g *Game) importAseprite(path string) {
+	readCh := make(chan io.ReadAsepriteResult)
+	go io.ReadAseprite(readCh, path)
+	readResult := <-readCh
+	close(readCh)
+	if readResult.Err != nil {
+		g.noticer.AddNotice(ui.ERROR, fmt.Sprintf("%s: %s", readResult.Err.Error(), path))
+		return
+	}
+	// タグがある場合はどのタグを読み込むか選ばせる
+	var tag *aseprite.Tag
+	if len(readResult.File.Tags) > 0 {
+		allFrames := "All frames"
+		items := []string{allFrames}
+		for _, t := range readResult.File.Tags {
+			items = append(items, t.Name)
 		}
-		sprites := sprite.NewSpritesFromRectMap(si, animationP.SpriteSheet)
-		g.startProject(animationP.Name)
-		for _, sprite := range sprites {
-			g.explorer.AppendSprite(sprite)
+		listCh := make(chan io.ListResult)
+		go io.List(listCh, "Select tag", "Select the tag to import", items)
+		listResult := <-listCh
+		close(listCh)
+		if listResult.Err != nil {
+			if listResult.Err.Error() != "dialog canceled" {
+				g.noticer.AddNotice(ui.WARN, listResult.Err.Error())
+			}
+			return
 		}
-		for i, part := range animationP.Animation.Parts {
-			if !part.Sprite.IsEmpty() {
-				for _, sprite := range sprites {
-					if sprite.Id() == part.Sprite.Id() {
-						animationP.Animation.Parts[i].Sprite = sprite
-						break
-					}
-				}
+		for i, t := range readResult.File.Tags {
+			if t.Name == listResult.Item && listResult.Item != allFrames {
+				tag = &readResult.File.Tags[i]
+				break
 			}
 		}
-		g.player.Import(animationP.Animation)
-		g.noticer.AddNotice(ui.INFO, fmt.Sprintf(`Project "%s" was imported with %d sprites!`, animationP.Name, len(sprites)))
-	} else {
-		g.startProject(animationP.Name)
-		g.player.Import(animationP.Animation)
-		g.noticer.AddNotice(ui.INFO, fmt.Sprintf(`Project "%s" was imported!`, animationP.Name))
+	}
+	a, sprites := io.AnimationFromAseprite(readResult.File, tag, ebiten.TPS())
+	g.startProject(readResult.Name)
+	for _, sprite := range sprites {
+		g.explorer.AppendSprite(sprite)
+	}
+	g.player.Import(a)
+	g.noticer.AddNotice(ui.INFO, fmt.Sprintf(`Project "%s" was imported from Aseprite with %d sprites!`, readResult.Name, len(sprites)))
+}
+
+func (g *Game) importPiskel(path string) {
+	readCh := make(chan io.ReadPiskelResult)
+	go io.ReadPiskel(readCh, path, ebiten.TPS())
+	readResult := <-readCh
+	close(readCh)
+	if readResult.Err != nil {
+		g.noticer.AddNotice(ui.ERROR, fmt.Sprintf("%s: %s", readResult.Err.Error(), path))
+		return
+	}
+	g.startProject(readResult.Name)
+	for _, sprite := range readResult.Sprites {
+		g.explorer.AppendSprite(sprite)
+	}
+	g.player.Import(readResult.Animation)
+	g.noticer.AddNotice(ui.INFO, fmt.Sprintf(`Project "%s" was imported from Piskel with %d sprites!`, readResult.Name, len(readResult.Sprites)))
+}
+
+// 書き出す形式を選ばせる
+func (g *Game) exportAs() {
+	if !g.player.RawAnimation().CanExport() {
+		return
+	}
+	g.player.Stop()
+	formats := []string{
+		"GIF",
+		"APNG",
+		"PNG sequence",
+		"Baked sprite sheet",
+		"Aseprite JSON",
+		"Godot SpriteFrames",
+		"Tiled tileset",
+		"Phaser atlas",
+		"HTML preview",
+		"Go source",
+	}
+	exporters := map[string]func(){
+		"GIF":                g.exportAsGif,
+		"APNG":               g.exportAsApng,
+		"PNG sequence":       g.exportAsPngSequence,
+		"Baked sprite sheet": g.exportAsBakedSheet,
+		"Aseprite JSON":      g.exportAsAseprite,
+		"Godot SpriteFrames": g.exportAsGodot,
+		"Tiled tileset":      g.exportAsTiled,
+		"Phaser atlas":       g.exportAsPhaser,
+		"HTML preview":       g.exportAsHtml,
+		"Go source":          g.exportAsGoSource,
+	}
+	listCh := make(chan io.ListResult)
+	go io.List(listCh, "Export as...", "Select the format", formats)
+	result := <-listCh
+	close(listCh)
+	if result.Err != nil {
+		if result.Err.Error() != "dialog canceled" {
+			g.noticer.AddNotice(ui.WARN, result.Err.Error())
+		}
+		return
+	}
+	if exporter, ok := exporters[result.Item]; ok {
+		exporter()
 	}
 }
 
@@ -408,6 +642,34 @@ func (g *Game) exportAsGif() {
 	if !g.player.RawAnimation().CanExport() {
 		return
 	}
+	raw := g.player.RawAnimation()
+	// 前回の設定をデフォルトにする
+	opts := animation.DefaultGifOptions()
+	if raw.GifOptions != nil {
+		opts = *raw.GifOptions
+	}
+	entryCh := make(chan io.EntryResult)
+	go io.Entry(entryCh, "GIF options", "Enter the options of GIF.\n"+
+		"loop: 0 (infinite), -1 (no loop) or count\n"+
+		"palette: local or global\n"+
+		"dither: none, floyd-steinberg or ordered\n"+
+		"bg: transparent or #rrggbb\n"+
+		"alpha: threshold of transparency (0-255)\n"+
+		"scale: integer upscale factor\n"+
+		"merge: true to merge consecutive identical frames", opts.String())
+	entryResult := <-entryCh
+	close(entryCh)
+	if entryResult.Err != nil {
+		if entryResult.Err.Error() != "dialog canceled" {
+			g.noticer.AddNotice(ui.ERROR, entryResult.Err.Error())
+		}
+		return
+	}
+	opts, err := animation.ParseGifOptions(entryResult.Input)
+	if err != nil {
+		g.noticer.AddNotice(ui.ERROR, err.Error())
+		return
+	}
 	pickCh := make(chan io.PickResult)
 	go io.Pick(pickCh, io.WithName("Save as..."), io.WithPatterns([]string{"*.gif"}), io.WithToSave(g.name+".gif"))
 	result := <-pickCh
@@ -418,7 +680,368 @@ func (g *Game) exportAsGif() {
 		}
 		return
 	}
-	err := g.player.RawAnimation().ExportAsGif(result.Path)
+	shortFrames, err := raw.ExportAsGif(result.Path, opts, ebiten.TPS())
+	if err != nil {
+		g.noticer.AddNotice(ui.ERROR, err.Error())
+		return
+	}
+	// 書き出せた設定はプロジェクトに保存される
+	if raw.GifOptions == nil || raw.GifOptions.String() != opts.String() {
+		g.player.MarkDirty()
+	}
+	raw.GifOptions = &opts
+	if len(shortFrames) > 0 {
+		g.noticer.AddNotice(ui.WARN, fmt.Sprintf("%d frames are shorter than %d/100 sec and may be played slower in browsers", len(shortFrames), animation.MinimumGifDelay))
+	}
+	g.noticer.AddNotice(ui.INFO, "Exported!")
+}
+
+func (g *Game) exportAsApng() {
+	if !g.player.RawAnimation().CanExport() {
+		return
+	}
+	entryCh := make(chan io.EntryResult)
+	go io.Entry(entryCh, "APNG options", "Enter the options of APNG.\n"+
+		"loop: 0 (infinite), -1 (no loop) or count", animation.DefaultApngOptions().String())
+	entryResult := <-entryCh
+	close(entryCh)
+	if entryResult.Err != nil {
+		if entryResult.Err.Error() != "dialog canceled" {
+			g.noticer.AddNotice(ui.ERROR, entryResult.Err.Error())
+		}
+		return
+	}
+	opts, err := animation.ParseApngOptions(entryResult.Input)
+	if err != nil {
+		g.noticer.AddNotice(ui.ERROR, err.Error())
+		return
+	}
+	pickCh := make(chan io.PickResult)
+	go io.Pick(pickCh, io.WithName("Save as..."), io.WithPatterns([]string{"*.png", "*.apng"}), io.WithToSave(g.name+"_apng.png"))
+	result := <-pickCh
+	close(pickCh)
+	if result.Err != nil {
+		if result.Err.Error() != "dialog canceled" {
+			g.noticer.AddNotice(ui.WARN, result.Err.Error())
+		}
+		return
+	}
+	err = g.player.RawAnimation().ExportAsApng(result.Path, opts, ebiten.TPS())
+	if err != nil {
+		g.noticer.AddNotice(ui.ERROR, err.Error())
+		return
+	}
+	g.noticer.AddNotice(ui.INFO, "Exported!")
+}
+
+func (g *Game) exportAsPngSequence() {
+	if !g.player.RawAnimation().CanExport() {
+		return
+	}
+	entryCh := make(chan io.EntryResult)
+	go io.Entry(entryCh, "PNG sequence options", "Enter the options of PNG sequence.\n"+
+		"mode: part (a file per part), tick (a file per tick) or fps (a file per 1/fps sec)\n"+
+		"fps: frame rate used when mode is fps", animation.DefaultSequenceOptions().String())
+	entryResult := <-entryCh
+	close(entryCh)
+	if entryResult.Err != nil {
+		if entryResult.Err.Error() != "dialog canceled" {
+			g.noticer.AddNotice(ui.ERROR, entryResult.Err.Error())
+		}
+		return
+	}
+	opts, err := animation.ParseSequenceOptions(entryResult.Input)
+	if err != nil {
+		g.noticer.AddNotice(ui.ERROR, err.Error())
+		return
+	}
+	selectDirCh := make(chan io.SelectDirResult)
+	go io.SelectDir(selectDirCh)
+	result := <-selectDirCh
+	close(selectDirCh)
+	if result.Err != nil {
+		if result.Err.Error() != "dialog canceled" {
+			g.noticer.AddNotice(ui.ERROR, result.Err.Error())
+		}
+		return
+	}
+	if io.IsExist(filepath.Join(result.Path, g.name+"_frames.json")) {
+		questionCh := make(chan io.QuestionResult)
+		go io.Question(questionCh, "Overwrite", "Overwrite existing files?")
+		result := <-questionCh
+		close(questionCh)
+		if !result.Answer {
+			return
+		}
+	}
+	err = g.player.RawAnimation().ExportAsPngSequence(result.Path, g.name, opts, ebiten.TPS())
+	if err != nil {
+		g.noticer.AddNotice(ui.ERROR, err.Error())
+		return
+	}
+	g.noticer.AddNotice(ui.INFO, "Exported!")
+}
+
+func (g *Game) exportAsBakedSheet() {
+	if !g.player.RawAnimation().CanExport() {
+		return
+	}
+	entryCh := make(chan io.EntryResult)
+	go io.Entry(entryCh, "Baked sprite sheet options", "Enter the options of baked sprite sheet.\n"+
+		"columns: frames per row (0 puts all frames in a row)\n"+
+		"padding: pixels around each frame\n"+
+		"order: row (left to right) or column (top to bottom)", animation.DefaultBakedOptions().String())
+	entryResult := <-entryCh
+	close(entryCh)
+	if entryResult.Err != nil {
+		if entryResult.Err.Error() != "dialog canceled" {
+			g.noticer.AddNotice(ui.ERROR, entryResult.Err.Error())
+		}
+		return
+	}
+	opts, err := animation.ParseBakedOptions(entryResult.Input)
+	if err != nil {
+		g.noticer.AddNotice(ui.ERROR, err.Error())
+		return
+	}
+	selectDirCh := make(chan io.SelectDirResult)
+	go io.SelectDir(selectDirCh)
+	result := <-selectDirCh
+	close(selectDirCh)
+	if result.Err != nil {
+		if result.Err.Error() != "dialog canceled" {
+			g.noticer.AddNotice(ui.ERROR, result.Err.Error())
+		}
+		return
+	}
+	if io.IsExist(filepath.Join(result.Path, g.name+"_baked.json")) {
+		questionCh := make(chan io.QuestionResult)
+		go io.Question(questionCh, "Overwrite", "Overwrite existing files?")
+		result := <-questionCh
+		close(questionCh)
+		if !result.Answer {
+			return
+		}
+	}
+	err = g.player.RawAnimation().ExportAsBakedSheet(result.Path, g.name, opts, ebiten.TPS())
+	if err != nil {
+		g.noticer.AddNotice(ui.ERROR, err.Error())
+		return
+	}
+	g.noticer.AddNotice(ui.INFO, "Exported!")
+}
+
+func (g *Game) exportAsAseprite() {
+	if !g.player.RawAnimation().CanExport() {
+		return
+	}
+	pickCh := make(chan io.PickResult)
+	go io.Pick(pickCh, io.WithName("Save as..."), io.WithPatterns([]string{"*.json"}), io.WithToSave(g.name+"_aseprite.json"))
+	result := <-pickCh
+	close(pickCh)
+	if result.Err != nil {
+		if result.Err.Error() != "dialog canceled" {
+			g.noticer.AddNotice(ui.WARN, result.Err.Error())
+		}
+		return
+	}
+	writeCh := make(chan io.WriteAsepriteResult)
+	go io.WriteAseprite(writeCh, result.Path, animation.AnimationP{
+		Name:      g.name,
+		Animation: g.player.RawAnimation(),
+	}, ebiten.TPS())
+	writeResult := <-writeCh
+	close(writeCh)
+	if writeResult.Err != nil {
+		g.noticer.AddNotice(ui.ERROR, writeResult.Err.Error())
+		return
+	}
+	if len(writeResult.InexactParts) > 0 {
+		g.noticer.AddNotice(ui.WARN, fmt.Sprintf("%d parts are scaled or reversed, which Aseprite JSON can not express", len(writeResult.InexactParts)))
+	}
+	g.noticer.AddNotice(ui.INFO, "Exported!")
+}
+
+func (g *Game) exportAsGodot() {
+	if !g.player.RawAnimation().CanExport() {
+		return
+	}
+	pickCh := make(chan io.PickResult)
+	go io.Pick(pickCh, io.WithName("Save as..."), io.WithPatterns([]string{"*.tres"}), io.WithToSave(g.name+"_godot.tres"))
+	result := <-pickCh
+	close(pickCh)
+	if result.Err != nil {
+		if result.Err.Error() != "dialog canceled" {
+			g.noticer.AddNotice(ui.WARN, result.Err.Error())
+		}
+		return
+	}
+	writeCh := make(chan io.WriteGodotResult)
+	go io.WriteGodot(writeCh, result.Path, animation.AnimationP{
+		Name:      g.name,
+		Animation: g.player.RawAnimation(),
+	}, ebiten.TPS())
+	writeResult := <-writeCh
+	close(writeCh)
+	if writeResult.Err != nil {
+		g.noticer.AddNotice(ui.ERROR, writeResult.Err.Error())
+		return
+	}
+	if len(writeResult.InexactParts) > 0 {
+		g.noticer.AddNotice(ui.WARN, fmt.Sprintf("%d parts are scaled, reversed or moved, which Godot SpriteFrames can not express", len(writeResult.InexactParts)))
+	}
+	g.noticer.AddNotice(ui.INFO, "Exported!")
+}
+
+func (g *Game) exportAsTiled() {
+	if !g.player.RawAnimation().CanExport() {
+		return
+	}
+	// スプライトごとのタイルにするか、合成済みのフレームを並べるか選ばせる
+	modes := map[string]animation.TiledMode{
+		"Sprites as tiles":        animation.TiledCollection,
+		"Rendered frames in grid": animation.TiledGridMode,
+	}
+	listCh := make(chan io.ListResult)
+	go io.List(listCh, "Tiled tileset", "Select how to make tiles", []string{"Sprites as tiles", "Rendered frames in grid"})
+	listResult := <-listCh
+	close(listCh)
+	if listResult.Err != nil {
+		if listResult.Err.Error() != "dialog canceled" {
+			g.noticer.AddNotice(ui.WARN, listResult.Err.Error())
+		}
+		return
+	}
+	mode, ok := modes[listResult.Item]
+	if !ok {
+		return
+	}
+	pickCh := make(chan io.PickResult)
+	go io.Pick(pickCh, io.WithName("Save as..."), io.WithPatterns([]string{"*.tsx"}), io.WithToSave(g.name+"_tiled.tsx"))
+	result := <-pickCh
+	close(pickCh)
+	if result.Err != nil {
+		if result.Err.Error() != "dialog canceled" {
+			g.noticer.AddNotice(ui.WARN, result.Err.Error())
+		}
+		return
+	}
+	writeCh := make(chan io.WriteTiledResult)
+	go io.WriteTiled(writeCh, result.Path, animation.AnimationP{
+		Name:      g.name,
+		Animation: g.player.RawAnimation(),
+	}, mode, ebiten.TPS())
+	writeResult := <-writeCh
+	close(writeCh)
+	if writeResult.Err != nil {
+		g.noticer.AddNotice(ui.ERROR, writeResult.Err.Error())
+		return
+	}
+	if len(writeResult.InexactParts) > 0 {
+		g.noticer.AddNotice(ui.WARN, fmt.Sprintf("%d parts are empty, scaled, reversed or moved, which Tiled tiles can not express", len(writeResult.InexactParts)))
+	}
+	g.noticer.AddNotice(ui.INFO, "Exported!")
+}
+
+func (g *Game) exportAsPhaser() {
+	if !g.player.RawAnimation().CanExport() {
+		return
+	}
+	pickCh := make(chan io.PickResult)
+	go io.Pick(pickCh, io.WithName("Save as..."), io.WithPatterns([]string{"*.json"}), io.WithToSave(g.name+"_atlas.json"))
+	result := <-pickCh
+	close(pickCh)
+	if result.Err != nil {
+		if result.Err.Error() != "dialog canceled" {
+			g.noticer.AddNotice(ui.WARN, result.Err.Error())
+		}
+		return
+	}
+	writeCh := make(chan io.WritePhaserResult)
+	go io.WritePhaser(writeCh, result.Path, animation.AnimationP{
+		Name:      g.name,
+		Animation: g.player.RawAnimation(),
+	}, ebiten.TPS())
+	writeResult := <-writeCh
+	close(writeCh)
+	if writeResult.Err != nil {
+		g.noticer.AddNotice(ui.ERROR, writeResult.Err.Error())
+		return
+	}
+	if len(writeResult.InexactParts) > 0 {
+		g.noticer.AddNotice(ui.WARN, fmt.Sprintf("%d parts are empty, scaled or reversed, which Phaser atlas can not express", len(writeResult.InexactParts)))
+	}
+	g.noticer.AddNotice(ui.INFO, "Exported!")
+}
+
+func (g *Game) exportAsHtml() {
+	if !g.player.RawAnimation().CanExport() {
+		return
+	}
+	pickCh := make(chan io.PickResult)
+	go io.Pick(pickCh, io.WithName("Save as..."), io.WithPatterns([]string{"*.html"}), io.WithToSave(g.name+".html"))
+	result := <-pickCh
+	close(pickCh)
+	if result.Err != nil {
+		if result.Err.Error() != "dialog canceled" {
+			g.noticer.AddNotice(ui.WARN, result.Err.Error())
+		}
+		return
+	}
+	writeCh := make(chan error)
+	go io.WriteHtml(writeCh, result.Path, animation.AnimationP{
+		Name:      g.name,
+		Animation: g.player.RawAnimation(),
+	}, ebiten.TPS())
+	err := <-writeCh
+	close(writeCh)
+	if err != nil {
+		g.noticer.AddNotice(ui.ERROR, err.Error())
+		return
+	}
+	g.noticer.AddNotice(ui.INFO, "Exported!")
+}
+
+func (g *Game) exportAsGoSource() {
+	if !g.player.RawAnimation().CanExport() {
+		return
+	}
+	entryCh := make(chan io.EntryResult)
+	go io.Entry(entryCh, "Go source", "Enter the package name", "anims")
+	entryResult := <-entryCh
+	close(entryCh)
+	if entryResult.Err != nil {
+		if entryResult.Err.Error() != "dialog canceled" {
+			g.noticer.AddNotice(ui.ERROR, entryResult.Err.Error())
+		}
+		return
+	}
+	selectDirCh := make(chan io.SelectDirResult)
+	go io.SelectDir(selectDirCh)
+	result := <-selectDirCh
+	close(selectDirCh)
+	if result.Err != nil {
+		if result.Err.Error() != "dialog canceled" {
+			g.noticer.AddNotice(ui.ERROR, result.Err.Error())
+		}
+		return
+	}
+	if io.IsExist(filepath.Join(result.Path, strings.ToLower(g.name)+".go")) {
+		questionCh := make(chan io.QuestionResult)
+		go io.Question(questionCh, "Overwrite", "Overwrite existing files?")
+		result := <-questionCh
+		close(questionCh)
+		if !result.Answer {
+			return
+		}
+	}
+	writeCh := make(chan error)
+	go io.WriteGoSource(writeCh, result.Path, strings.TrimSpace(entryResult.Input), animation.AnimationP{
+		Name:      g.name,
+		Animation: g.player.RawAnimation(),
+	}, ebiten.TPS())
+	err := <-writeCh
+	close(writeCh)
 	if err != nil {
 		g.noticer.AddNotice(ui.ERROR, err.Error())
 		return
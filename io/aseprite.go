@@ -0,0 +1,88 @@
+package io
+
+import (
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aethiopicuschan/odori/animation"
+	"github.com/aethiopicuschan/odori/aseprite"
+	"github.com/aethiopicuschan/odori/sprite"
+)
+
+type WriteAsepriteResult struct {
+	// Asepriteの形式で正確に表せなかったパーツの番号
+	InexactParts []int
+	Err          error
+}
+
+// pathにAseprite形式のJSONを、同じディレクトリに同じ名前のPNGでスプライトシートを書き出す
+func WriteAseprite(ch chan WriteAsepriteResult, path string, animationP animation.AnimationP, tps int) {
+	result := WriteAsepriteResult{}
+	defer func() {
+		ch <- result
+	}()
+	imageName := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)) + ".png"
+	rectsMap, size, err := writeExportSheet(animationP.Animation.Sprites(), filepath.Join(filepath.Dir(path), imageName))
+	if err != nil {
+		result.Err = err
+		return
+	}
+	sheet, inexactParts := animation.NewAsepriteSheet(animationP, rectsMap, imageName, size, tps)
+	bytes, err := animation.MarshalJson(sheet)
+	if err != nil {
+		result.Err = err
+		return
+	}
+	result.Err = os.WriteFile(path, bytes, 0644)
+	result.InexactParts = inexactParts
+}
+
+type ReadAsepriteResult struct {
+	Name string
+	File *aseprite.File
+	Path string
+	Err  error
+}
+
+// Asepriteのファイル(.aseprite/.ase)を読み込む
+func ReadAseprite(ch chan ReadAsepriteResult, path string) {
+	result := ReadAsepriteResult{
+		Path: path,
+	}
+	defer func() {
+		ch <- result
+	}()
+	file, err := os.Open(path)
+	if err != nil {
+		result.Err = err
+		return
+	}
+	defer file.Close()
+	result.File, result.Err = aseprite.Decode(file)
+	if result.Err != nil {
+		return
+	}
+	result.Name = NameFromPath(path)
+}
+
+// Asepriteのフレームからアニメーションを作る
+// tagがnilの場合は全てのフレームを使う
+func AnimationFromAseprite(f *aseprite.File, tag *aseprite.Tag, tps int) (*animation.Animation, []sprite.Sprite) {
+	indexes := []int{}
+	if tag != nil {
+		indexes = tag.FrameIndexes()
+	} else {
+		for i := range f.Frames {
+			indexes = append(indexes, i)
+		}
+	}
+	frames := []*image.RGBA{}
+	durations := []int{}
+	for _, i := range indexes {
+		frames = append(frames, f.Frames[i].Image)
+		durations = append(durations, f.Frames[i].Duration)
+	}
+	return animationFromFrames(frames, durations, f.Width, f.Height, tps)
+}
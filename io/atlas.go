@@ -0,0 +1,164 @@
+package io
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"os"
+	"path/filepath"
+
+	"github.com/aethiopicuschan/odori/sprite"
+)
+
+// TexturePackerのJSON Hash/JSON Array形式のアトラス
+// PixiJSやPhaserも同じ形式を使う
+type atlasFile struct {
+	// JSON Hashではオブジェクト、JSON Arrayでは配列
+	Frames json.RawMessage `json:"frames"`
+	Meta   struct {
+		Image string `json:"image"`
+	} `json:"meta"`
+	// Phaserのマルチアトラスでは画像ごとに分かれている
+	Textures []struct {
+		Image  string          `json:"image"`
+		Frames json.RawMessage `json:"frames"`
+	} `json:"textures"`
+}
+
+type atlasFrame struct {
+	Filename         string    `json:"filename"`
+	Frame            atlasRect `json:"frame"`
+	Rotated          bool      `json:"rotated"`
+	Trimmed          bool      `json:"trimmed"`
+	SpriteSourceSize atlasRect `json:"spriteSourceSize"`
+	SourceSize       struct {
+		W int `json:"w"`
+		H int `json:"h"`
+	} `json:"sourceSize"`
+}
+
+type atlasRect struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+// アトラスのJSONと同じディレクトリにある画像を読み込み、フレームごとに名前付きのスプライトにする
+func ReadAtlas(ch chan ReadSpriteSheetResult, path string) {
+	result := ReadSpriteSheetResult{}
+	defer func() {
+		ch <- result
+	}()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		result.Err = err
+		return
+	}
+	atlas := atlasFile{}
+	err = json.Unmarshal(data, &atlas)
+	if err != nil {
+		result.Err = err
+		return
+	}
+	type texture struct {
+		image  string
+		frames json.RawMessage
+	}
+	textures := []texture{}
+	if len(atlas.Frames) > 0 {
+		textures = append(textures, texture{atlas.Meta.Image, atlas.Frames})
+	}
+	for _, t := range atlas.Textures {
+		textures = append(textures, texture{t.Image, t.Frames})
+	}
+	if len(textures) == 0 {
+		result.Err = errors.New("no frames in the atlas")
+		return
+	}
+	for _, t := range textures {
+		if t.image == "" {
+			result.Err = errors.New("meta.image is missing")
+			return
+		}
+		var frames []atlasFrame
+		frames, err = decodeAtlasFrames(t.frames)
+		if err != nil {
+			result.Err = err
+			return
+		}
+		var img image.Image
+		img, err = ReadPng(filepath.Join(filepath.Dir(path), t.image))
+		if err != nil {
+			result.Err = err
+			return
+		}
+		for _, frame := range frames {
+			result.Sprites = append(result.Sprites, sprite.NewSpriteWithName(restoreAtlasFrame(img, frame), frame.Filename))
+		}
+	}
+}
+
+// JSON Hashの場合もファイルに書かれた順番を保ってフレームを返す
+func decodeAtlasFrames(data json.RawMessage) (frames []atlasFrame, err error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &frames)
+		return
+	}
+	dec := json.NewDecoder(bytes.NewReader(trimmed))
+	token, err := dec.Token()
+	if err != nil {
+		return
+	}
+	if token != json.Delim('{') {
+		err = errors.New("frames must be an object or an array")
+		return
+	}
+	for dec.More() {
+		token, err = dec.Token()
+		if err != nil {
+			return
+		}
+		name, ok := token.(string)
+		if !ok {
+			err = fmt.Errorf("invalid frame name: %v", token)
+			return
+		}
+		frame := atlasFrame{}
+		err = dec.Decode(&frame)
+		if err != nil {
+			return
+		}
+		frame.Filename = name
+		frames = append(frames, frame)
+	}
+	return
+}
+
+// 回転と余白の切り取りを元に戻した画像を返す
+func restoreAtlasFrame(img image.Image, frame atlasFrame) image.Image {
+	w, h := frame.Frame.W, frame.Frame.H
+	origin := img.Bounds().Min.Add(image.Pt(frame.Frame.X, frame.Frame.Y))
+	trimmed := image.NewRGBA(image.Rect(0, 0, w, h))
+	if frame.Rotated {
+		// アトラスには時計回りに90度回転して格納されている
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				trimmed.Set(x, y, img.At(origin.X+h-1-y, origin.Y+x))
+			}
+		}
+	} else {
+		draw.Draw(trimmed, trimmed.Bounds(), img, origin, draw.Src)
+	}
+	if !frame.Trimmed || frame.SourceSize.W <= 0 || frame.SourceSize.H <= 0 {
+		return trimmed
+	}
+	restored := image.NewRGBA(image.Rect(0, 0, frame.SourceSize.W, frame.SourceSize.H))
+	offset := image.Pt(frame.SpriteSourceSize.X, frame.SpriteSourceSize.Y)
+	draw.Draw(restored, trimmed.Bounds().Add(offset), trimmed, image.Point{}, draw.Src)
+	return restored
+}
@@ -0,0 +1,97 @@
+package io
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// 元の画像の画素ごとに異なる色
+func atlasColor(x, y int) color.RGBA {
+	return color.RGBA{R: uint8(10 + x), G: uint8(20 + y), A: 255}
+}
+
+func TestReadAtlasRotatedTrimmed(t *testing.T) {
+	dir := t.TempDir()
+	// 5x4の画像の(1, 1)から3x2だけが不透明で、アトラスには時計回りに90度回転して(2, 1)に2x3で格納されている
+	atlas := image.NewRGBA(image.Rect(0, 0, 6, 6))
+	atlas.Set(0, 0, color.RGBA{B: 255, A: 255})
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 3; x++ {
+			atlas.Set(2+(2-1-y), 1+x, atlasColor(x, y))
+		}
+	}
+	err := WritePng(atlas, filepath.Join(dir, "atlas.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 名前の順番ではなくファイルに書かれた順番で読み込まれる
+	data := `{
+	"frames": {
+		"walk_1": {
+			"frame": {"x": 2, "y": 1, "w": 3, "h": 2},
+			"rotated": true,
+			"trimmed": true,
+			"spriteSourceSize": {"x": 1, "y": 1, "w": 3, "h": 2},
+			"sourceSize": {"w": 5, "h": 4}
+		},
+		"dot": {
+			"frame": {"x": 0, "y": 0, "w": 1, "h": 1},
+			"rotated": false,
+			"trimmed": false,
+			"spriteSourceSize": {"x": 0, "y": 0, "w": 1, "h": 1},
+			"sourceSize": {"w": 1, "h": 1}
+		}
+	},
+	"meta": {"image": "atlas.png"}
+}`
+	path := filepath.Join(dir, "atlas.json")
+	err = os.WriteFile(path, []byte(data), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ch := make(chan ReadSpriteSheetResult)
+	go ReadAtlas(ch, path)
+	result := <-ch
+	close(ch)
+	if result.Err != nil {
+		t.Fatal(result.Err)
+	}
+	if len(result.Sprites) != 2 {
+		t.Fatalf("len(sprites) = %d, want 2", len(result.Sprites))
+	}
+
+	walk := result.Sprites[0]
+	if walk.Name() != "walk_1" {
+		t.Fatalf("name = %q, want %q", walk.Name(), "walk_1")
+	}
+	if walk.Bounds() != image.Rect(0, 0, 5, 4) {
+		t.Fatalf("bounds = %v, want %v", walk.Bounds(), image.Rect(0, 0, 5, 4))
+	}
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 5; x++ {
+			want := color.RGBA{}
+			if x >= 1 && x < 4 && y >= 1 && y < 3 {
+				want = atlasColor(x-1, y-1)
+			}
+			got := color.RGBAModel.Convert(walk.Source.At(x, y)).(color.RGBA)
+			if got != want {
+				t.Errorf("pixel (%d, %d) = %v, want %v", x, y, got, want)
+			}
+		}
+	}
+
+	dot := result.Sprites[1]
+	if dot.Name() != "dot" {
+		t.Fatalf("name = %q, want %q", dot.Name(), "dot")
+	}
+	if dot.Bounds() != image.Rect(0, 0, 1, 1) {
+		t.Fatalf("bounds = %v, want %v", dot.Bounds(), image.Rect(0, 0, 1, 1))
+	}
+	if got := color.RGBAModel.Convert(dot.Source.At(0, 0)).(color.RGBA); got != (color.RGBA{B: 255, A: 255}) {
+		t.Fatalf("pixel = %v, want blue", got)
+	}
+}
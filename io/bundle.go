@@ -0,0 +1,131 @@
+package io
+
+import (
+	"archive/zip"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+
+	"github.com/aethiopicuschan/odori/animation"
+	"github.com/aethiopicuschan/odori/constant"
+	"github.com/aethiopicuschan/odori/sprite"
+)
+
+// プロジェクトを1つの.odoriファイルに書き出す
+// 書き出しに失敗しても元のファイルを壊さないように、一時ファイルに書いてから置き換える
+func WriteBundle(ch chan error, path, name string, a *animation.Animation, library []sprite.Sprite) {
+	var err error
+	defer func() {
+		ch <- err
+	}()
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".odori-*")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+	err = writeBundle(tmp, name, a, library)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return
+	}
+	// CreateTempは所有者しか読めないので、他の出力と同じか既存のファイルと同じにする
+	mode := os.FileMode(0644)
+	if info, statErr := os.Stat(path); statErr == nil {
+		mode = info.Mode().Perm()
+	}
+	err = os.Chmod(tmp.Name(), mode)
+	if err != nil {
+		return
+	}
+	err = os.Rename(tmp.Name(), path)
+}
+
+func writeBundle(file *os.File, name string, a *animation.Animation, library []sprite.Sprite) (err error) {
+	zw := zip.NewWriter(file)
+	spriteSheet := map[string]image.Rectangle{}
+	sprites, entries := animation.NewLibrary(library, a)
+	if len(sprites) != 0 {
+		var img image.Image
+		img, spriteSheet, err = MergeSprites(sprites)
+		if err != nil {
+			return
+		}
+		err = writeZipPng(zw, animation.BundleSheetName, img)
+		if err != nil {
+			return
+		}
+	}
+	err = writeZipJson(zw, animation.BundleManifestName, animation.AnimationP{
+		FormatVersion: constant.FormatVersion,
+		Name:          name,
+		Animation:     a,
+		SpriteSheet:   spriteSheet,
+		Library:       entries,
+	})
+	if err != nil {
+		return
+	}
+	err = writeZipJson(zw, animation.BundleSpritesName, animation.NewBundleSprites(sprites))
+	if err != nil {
+		return
+	}
+	if a.CanExport() {
+		err = writeZipPng(zw, animation.BundleThumbnailName, a.RenderFrame(a.Parts[0]))
+		if err != nil {
+			return
+		}
+	}
+	err = zw.Close()
+	return
+}
+
+// 同じ内容なら同じバイト列になるように、更新日時は入れない
+func zipHeader(name string, method uint16) *zip.FileHeader {
+	return &zip.FileHeader{
+		Name:   name,
+		Method: method,
+	}
+}
+
+func writeZipJson(zw *zip.Writer, name string, v any) (err error) {
+	w, err := zw.CreateHeader(zipHeader(name, zip.Deflate))
+	if err != nil {
+		return
+	}
+	bytes, err := animation.MarshalJson(v)
+	if err != nil {
+		return
+	}
+	_, err = w.Write(bytes)
+	return
+}
+
+func writeZipPng(zw *zip.Writer, name string, img image.Image) (err error) {
+	// PNGは圧縮済みなのでそのまま格納する
+	w, err := zw.CreateHeader(zipHeader(name, zip.Store))
+	if err != nil {
+		return
+	}
+	err = png.Encode(w, img)
+	return
+}
+
+// .odoriファイルを読み込む
+func ReadBundle(ch chan ReadProjectResult, path string) {
+	result := ReadProjectResult{
+		Path: path,
+	}
+	defer func() {
+		ch <- result
+	}()
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		result.Err = err
+		return
+	}
+	defer zr.Close()
+	result.AnimationP, result.Sprites, result.Err = animation.ReadBundle(zr)
+}
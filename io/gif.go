@@ -0,0 +1,125 @@
+package io
+
+import (
+	"image"
+	"image/draw"
+	"image/gif"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/aethiopicuschan/odori/animation"
+	"github.com/aethiopicuschan/odori/sprite"
+)
+
+type ReadGifResult struct {
+	Name      string
+	Animation *animation.Animation
+	Sprites   []sprite.Sprite
+	Path      string
+	Err       error
+}
+
+// GIFを読み込み、重複しないフレームをスプライトにしたアニメーションを作る
+func ReadGif(ch chan ReadGifResult, path string, tps int) {
+	result := ReadGifResult{
+		Path: path,
+	}
+	defer func() {
+		ch <- result
+	}()
+	file, err := os.Open(path)
+	if err != nil {
+		result.Err = err
+		return
+	}
+	defer file.Close()
+	g, err := gif.DecodeAll(file)
+	if err != nil {
+		result.Err = err
+		return
+	}
+	result.Name = NameFromPath(path)
+	result.Animation, result.Sprites = animationFromFrames(compositeGif(g), gifDurations(g), g.Config.Width, g.Config.Height, tps)
+}
+
+// ファイル名からプロジェクト名として使える文字だけを取り出す
+func NameFromPath(path string) string {
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	name := regexp.MustCompile("[^0-9a-zA-Z]").ReplaceAllString(base, "")
+	if name == "" {
+		name = "animation"
+	}
+	return name
+}
+
+// 各フレームをDisposalに従って論理スクリーンに重ねた画像を返す
+func compositeGif(g *gif.GIF) (frames []*image.RGBA) {
+	screen := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	canvas := image.NewRGBA(screen)
+	for i, img := range g.Image {
+		disposal := byte(0)
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		var previous *image.RGBA
+		if disposal == gif.DisposalPrevious {
+			previous = image.NewRGBA(screen)
+			copy(previous.Pix, canvas.Pix)
+		}
+		draw.Draw(canvas, img.Bounds(), img, img.Bounds().Min, draw.Over)
+		frame := image.NewRGBA(screen)
+		copy(frame.Pix, canvas.Pix)
+		frames = append(frames, frame)
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, img.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+	return
+}
+
+// 各フレームの表示時間をミリ秒で返す
+func gifDurations(g *gif.GIF) (durations []int) {
+	for i := range g.Image {
+		delay := 0
+		if i < len(g.Delay) {
+			delay = g.Delay[i]
+		}
+		// ブラウザに合わせて、短すぎるDelayは0.1秒として扱う
+		if delay <= 1 {
+			delay = 10
+		}
+		durations = append(durations, delay*10)
+	}
+	return
+}
+
+// 合成済みのフレームとミリ秒単位の表示時間からアニメーションを作る
+// 同じ画素のフレームは1つのスプライトを共有する
+func animationFromFrames(frames []*image.RGBA, durations []int, width, height, tps int) (a *animation.Animation, sprites []sprite.Sprite) {
+	a = animation.NewAnimation()
+	a.Width = width
+	a.Height = height
+	spriteIndexes := map[string]int{}
+	// 0 Tickのパーツは作れないので最低1 Tickにする
+	lengths := animation.ConvertDurations(durations, 1000, tps, 1)
+	for i, frame := range frames {
+		key := string(frame.Pix)
+		index, ok := spriteIndexes[key]
+		if !ok {
+			index = len(sprites)
+			spriteIndexes[key] = index
+			sprites = append(sprites, sprite.NewSprite(frame))
+		}
+		part := animation.NewPart(sprites[index], lengths[i])
+		// キャンバスの中心に合わせる際に奇数サイズだと半ピクセルずれるので補正する
+		part.DiffX = width % 2
+		part.DiffY = height % 2
+		a.Parts = append(a.Parts, part)
+	}
+	return
+}
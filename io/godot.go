@@ -0,0 +1,35 @@
+package io
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aethiopicuschan/odori/animation"
+)
+
+type WriteGodotResult struct {
+	// Godotで正確に表せなかったパーツの番号
+	InexactParts []int
+	Err          error
+}
+
+// pathにGodotのSpriteFrames(.tres)を、同じディレクトリに同じ名前のPNGでスプライトシートを書き出す
+// プロジェクトのスプライトシートと同じ名前になる場合は書き出さない
+func WriteGodot(ch chan WriteGodotResult, path string, animationP animation.AnimationP, tps int) {
+	result := WriteGodotResult{}
+	defer func() {
+		ch <- result
+	}()
+	imageName := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)) + ".png"
+	rectsMap, _, err := writeExportSheet(animationP.Animation.Sprites(), filepath.Join(filepath.Dir(path), imageName))
+	if err != nil {
+		result.Err = err
+		return
+	}
+	animationP.SpriteSheet = rectsMap
+	// 相対パスは.tresのあるディレクトリから解決される
+	tres, inexactParts := animation.NewGodotSpriteFrames(animationP, imageName, tps)
+	result.Err = os.WriteFile(path, []byte(tres), 0644)
+	result.InexactParts = inexactParts
+}
@@ -0,0 +1,46 @@
+package io
+
+import (
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aethiopicuschan/odori/animation"
+)
+
+// dirにGoのパッケージとしてスプライトシートとソースコードを書き出す
+// 共通の型のファイルと"<name>.go"と"<name>.png"を書き出す
+func WriteGoSource(ch chan error, dir, pkg string, animationP animation.AnimationP, tps int) {
+	var err error
+	defer func() {
+		ch <- err
+	}()
+	base := strings.ToLower(animationP.Name)
+	rectsMap := map[string]image.Rectangle{}
+	sheetName := ""
+	var img image.Image
+	if sprites := animationP.Animation.Sprites(); len(sprites) != 0 {
+		img, rectsMap, err = MergeSprites(sprites)
+		if err != nil {
+			return
+		}
+		sheetName = base + ".png"
+	}
+	// パッケージ名などが不正なときに何も書き出さないように、ソースコードを先に作る
+	types, source, err := animation.NewGoSource(animationP, rectsMap, pkg, sheetName, tps)
+	if err != nil {
+		return
+	}
+	if img != nil {
+		err = WritePng(img, filepath.Join(dir, sheetName))
+		if err != nil {
+			return
+		}
+	}
+	err = os.WriteFile(filepath.Join(dir, animation.GoTypesFileName), types, 0644)
+	if err != nil {
+		return
+	}
+	err = os.WriteFile(filepath.Join(dir, base+".go"), source, 0644)
+}
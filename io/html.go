@@ -0,0 +1,36 @@
+package io
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"os"
+
+	"github.com/aethiopicuschan/odori/animation"
+)
+
+// スプライトシートを埋め込んだHTMLのプレビューをpathに書き出す
+func WriteHtml(ch chan error, path string, animationP animation.AnimationP, tps int) {
+	var err error
+	defer func() {
+		ch <- err
+	}()
+	rectsMap := map[string]image.Rectangle{}
+	sheet := &bytes.Buffer{}
+	if sprites := animationP.Animation.Sprites(); len(sprites) != 0 {
+		var img image.Image
+		img, rectsMap, err = MergeSprites(sprites)
+		if err != nil {
+			return
+		}
+		err = png.Encode(sheet, img)
+		if err != nil {
+			return
+		}
+	}
+	html, err := animation.NewHtmlPreview(animationP, sheet.Bytes(), rectsMap, tps)
+	if err != nil {
+		return
+	}
+	err = os.WriteFile(path, html, 0644)
+}
@@ -0,0 +1,16 @@
+package io
+
+import "github.com/ncruces/zenity"
+
+type ListResult struct {
+	Item string
+	Err  error
+}
+
+func List(ch chan ListResult, title, text string, items []string) {
+	result := ListResult{}
+	defer func() {
+		ch <- result
+	}()
+	result.Item, result.Err = zenity.List(text, items, zenity.Title(title))
+}
@@ -0,0 +1,50 @@
+package io
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aethiopicuschan/odori/animation"
+)
+
+type WritePhaserResult struct {
+	// Phaserの形式で正確に表せなかったパーツの番号
+	InexactParts []int
+	Err          error
+}
+
+// pathにPhaserのマルチアトラスを書き出す
+// 同じディレクトリに同じ名前のPNGでスプライトシートを、"<name>_anims.json"でアニメーションを書き出す
+func WritePhaser(ch chan WritePhaserResult, path string, animationP animation.AnimationP, tps int) {
+	result := WritePhaserResult{}
+	defer func() {
+		ch <- result
+	}()
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	dir := filepath.Dir(path)
+	imageName := base + ".png"
+	rectsMap, size, err := writeExportSheet(animationP.Animation.Sprites(), filepath.Join(dir, imageName))
+	if err != nil {
+		result.Err = err
+		return
+	}
+	atlas, anims, inexactParts := animation.NewPhaserAtlas(animationP, rectsMap, imageName, size, tps)
+	bytes, err := animation.MarshalJson(atlas)
+	if err != nil {
+		result.Err = err
+		return
+	}
+	err = os.WriteFile(path, bytes, 0644)
+	if err != nil {
+		result.Err = err
+		return
+	}
+	bytes, err = animation.MarshalJson(anims)
+	if err != nil {
+		result.Err = err
+		return
+	}
+	result.Err = os.WriteFile(filepath.Join(dir, base+"_anims.json"), bytes, 0644)
+	result.InexactParts = inexactParts
+}
@@ -0,0 +1,183 @@
+package io
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/png"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/aethiopicuschan/odori/animation"
+	"github.com/aethiopicuschan/odori/sprite"
+)
+
+// .piskelのJSON
+type piskelFile struct {
+	ModelVersion int `json:"modelVersion"`
+	Piskel       struct {
+		Name   string `json:"name"`
+		FPS    int    `json:"fps"`
+		Width  int    `json:"width"`
+		Height int    `json:"height"`
+		// 各レイヤーはJSONの文字列として埋め込まれている
+		Layers       []string `json:"layers"`
+		HiddenFrames []int    `json:"hiddenFrames"`
+	} `json:"piskel"`
+}
+
+type piskelLayer struct {
+	Name       string        `json:"name"`
+	Opacity    *float64      `json:"opacity"`
+	FrameCount int           `json:"frameCount"`
+	Chunks     []piskelChunk `json:"chunks"`
+	// modelVersion 1では全フレームを横に並べた画像が直接入っている
+	Base64PNG string `json:"base64PNG"`
+}
+
+type piskelChunk struct {
+	// layout[列][行]がフレーム番号
+	Layout    [][]int `json:"layout"`
+	Base64PNG string  `json:"base64PNG"`
+}
+
+type ReadPiskelResult struct {
+	Name      string
+	Animation *animation.Animation
+	Sprites   []sprite.Sprite
+	Path      string
+	Err       error
+}
+
+// Piskelのプロジェクトを読み込み、レイヤーを合成したフレームからアニメーションを作る
+func ReadPiskel(ch chan ReadPiskelResult, path string, tps int) {
+	result := ReadPiskelResult{
+		Path: path,
+	}
+	defer func() {
+		ch <- result
+	}()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		result.Err = err
+		return
+	}
+	p := piskelFile{}
+	err = json.Unmarshal(data, &p)
+	if err != nil {
+		result.Err = err
+		return
+	}
+	frames, err := compositePiskel(p)
+	if err != nil {
+		result.Err = err
+		return
+	}
+	// 非表示のフレームは書き出されないので読み込まない
+	hidden := map[int]bool{}
+	for _, i := range p.Piskel.HiddenFrames {
+		hidden[i] = true
+	}
+	visibles := []*image.RGBA{}
+	for i, frame := range frames {
+		if !hidden[i] {
+			visibles = append(visibles, frame)
+		}
+	}
+	if len(visibles) == 0 {
+		result.Err = errors.New("no visible frames")
+		return
+	}
+	result.Name = regexp.MustCompile("[^0-9a-zA-Z]").ReplaceAllString(p.Piskel.Name, "")
+	if result.Name == "" {
+		result.Name = NameFromPath(path)
+	}
+	result.Animation, result.Sprites = animationFromFrames(visibles, piskelDurations(len(visibles), p.Piskel.FPS), p.Piskel.Width, p.Piskel.Height, tps)
+}
+
+// 全てのレイヤーを下から順に重ねたフレームを返す
+func compositePiskel(p piskelFile) (frames []*image.RGBA, err error) {
+	w, h := p.Piskel.Width, p.Piskel.Height
+	if w <= 0 || h <= 0 {
+		err = errors.New("invalid size")
+		return
+	}
+	for _, raw := range p.Piskel.Layers {
+		layer := piskelLayer{}
+		err = json.Unmarshal([]byte(raw), &layer)
+		if err != nil {
+			return
+		}
+		opacity := 1.0
+		if layer.Opacity != nil {
+			opacity = *layer.Opacity
+		}
+		mask := image.NewUniform(color.Alpha{uint8(opacity*0xff + 0.5)})
+		chunks := layer.Chunks
+		if len(chunks) == 0 && layer.Base64PNG != "" {
+			// 横一列に並んでいる
+			layout := [][]int{}
+			for i := 0; i < layer.FrameCount; i++ {
+				layout = append(layout, []int{i})
+			}
+			chunks = []piskelChunk{{Layout: layout, Base64PNG: layer.Base64PNG}}
+		}
+		for len(frames) < layer.FrameCount {
+			frames = append(frames, image.NewRGBA(image.Rect(0, 0, w, h)))
+		}
+		for _, chunk := range chunks {
+			var img image.Image
+			img, err = decodeDataURL(chunk.Base64PNG)
+			if err != nil {
+				err = fmt.Errorf("layer %s: %w", layer.Name, err)
+				return
+			}
+			origin := img.Bounds().Min
+			for col, rows := range chunk.Layout {
+				for row, index := range rows {
+					if index < 0 || index >= len(frames) {
+						continue
+					}
+					sp := origin.Add(image.Pt(col*w, row*h))
+					draw.DrawMask(frames[index], frames[index].Bounds(), img, sp, mask, image.Point{}, draw.Over)
+				}
+			}
+		}
+	}
+	if len(frames) == 0 {
+		err = errors.New("no frames")
+	}
+	return
+}
+
+// "data:image/png;base64,..."の形式の画像を読み込む
+func decodeDataURL(url string) (img image.Image, err error) {
+	_, data, ok := strings.Cut(url, ",")
+	if !ok {
+		data = url
+	}
+	decoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return
+	}
+	img, _, err = image.Decode(bytes.NewReader(decoded))
+	return
+}
+
+// fpsから各フレームのミリ秒単位の表示時間を求める
+func piskelDurations(count, fps int) (durations []int) {
+	if fps <= 0 {
+		fps = 12
+	}
+	frames := make([]int, count)
+	for i := range frames {
+		frames[i] = 1
+	}
+	return animation.ConvertDurations(frames, fps, 1000, 0)
+}
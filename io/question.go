@@ -1,6 +1,10 @@
 package io
 
-import "github.com/ncruces/zenity"
+import (
+	"errors"
+
+	"github.com/ncruces/zenity"
+)
 
 type QuestionResult struct {
 	Answer bool
@@ -14,3 +18,20 @@ func Question(ch chan QuestionResult, title, text string) {
 	err := zenity.Question(text, zenity.Title(title))
 	result.Answer = err == nil
 }
+
+type QuestionWithExtraResult struct {
+	Answer bool
+	// 追加のボタンが押された
+	Extra bool
+}
+
+// OK・追加のボタン・キャンセルの3択で聞く
+func QuestionWithExtra(ch chan QuestionWithExtraResult, title, text, okLabel, extraLabel, cancelLabel string) {
+	result := QuestionWithExtraResult{}
+	defer func() {
+		ch <- result
+	}()
+	err := zenity.Question(text, zenity.Title(title), zenity.OKLabel(okLabel), zenity.ExtraButton(extraLabel), zenity.CancelLabel(cancelLabel))
+	result.Answer = err == nil
+	result.Extra = errors.Is(err, zenity.ErrExtraButton)
+}
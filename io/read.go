@@ -2,8 +2,10 @@ package io
 
 import (
 	"os"
+	"path/filepath"
 
 	"github.com/aethiopicuschan/kaban/detection"
+	"github.com/aethiopicuschan/odori/animation"
 	"github.com/aethiopicuschan/odori/sprite"
 )
 
@@ -66,3 +68,20 @@ func ReadSpriteSheet(ch chan ReadSpriteSheetResult, path string) {
 		result.Sprites = sprite.NewSpriteFromRects(img, rects)
 	}
 }
+
+type ReadProjectResult struct {
+	AnimationP animation.AnimationP
+	Sprites    []sprite.Sprite
+	Path       string
+	Err        error
+}
+
+func ReadProject(ch chan ReadProjectResult, path string) {
+	result := ReadProjectResult{
+		Path: path,
+	}
+	defer func() {
+		ch <- result
+	}()
+	result.AnimationP, result.Sprites, result.Err = animation.ReadProject(os.DirFS(filepath.Dir(path)), filepath.Base(path))
+}
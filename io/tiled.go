@@ -0,0 +1,63 @@
+package io
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aethiopicuschan/odori/animation"
+)
+
+type WriteTiledResult struct {
+	// Tiledで正確に表せなかったパーツの番号
+	InexactParts []int
+	Err          error
+}
+
+// pathにTiledのタイルセット(.tsx)を、同じディレクトリに同じ名前のPNGで画像を書き出す
+// プロジェクトのスプライトシートと同じ名前になる場合は書き出さない
+func WriteTiled(ch chan WriteTiledResult, path string, animationP animation.AnimationP, mode animation.TiledMode, tps int) {
+	result := WriteTiledResult{}
+	defer func() {
+		ch <- result
+	}()
+	imageName := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)) + ".png"
+	imagePath := filepath.Join(filepath.Dir(path), imageName)
+	var tileset animation.TiledTileset
+	switch mode {
+	case animation.TiledCollection:
+		rectsMap, size, err := writeExportSheet(animationP.Animation.Sprites(), imagePath)
+		if err != nil {
+			result.Err = err
+			return
+		}
+		animationP.SpriteSheet = rectsMap
+		tileset, result.InexactParts = animation.NewTiledCollection(animationP, imageName, size, tps)
+	case animation.TiledGridMode:
+		// Tiledのタイル番号は行優先なので、それに合わせて並べる
+		opts := animation.DefaultBakedOptions()
+		sheet, frames, columns, _, err := animationP.Animation.BakeSheet(opts, tps)
+		if err != nil {
+			result.Err = err
+			return
+		}
+		err = writeExportPng(sheet, imagePath)
+		if err != nil {
+			result.Err = err
+			return
+		}
+		tileset = animation.NewTiledGrid(animationP, imageName, sheet.Bounds(), frames, columns, opts.Padding, tps)
+	default:
+		result.Err = fmt.Errorf("unknown mode: %s", mode)
+		return
+	}
+	bytes, err := xml.MarshalIndent(tileset, "", " ")
+	if err != nil {
+		result.Err = err
+		return
+	}
+	bytes = append([]byte(xml.Header), bytes...)
+	result.Err = os.WriteFile(path, append(bytes, '\n'), 0644)
+}
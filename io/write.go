@@ -1,10 +1,16 @@
 package io
 
 import (
+	"encoding/json"
+	"fmt"
 	"image"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/aethiopicuschan/kaban/merge"
+	"github.com/aethiopicuschan/odori/animation"
+	"github.com/aethiopicuschan/odori/constant"
 	"github.com/aethiopicuschan/odori/sprite"
 )
 
@@ -19,9 +25,31 @@ func Write(ch chan error, bytes []byte, path string) {
 	ch <- err
 }
 
+// スプライトを1枚の画像にまとめる
+// 返り値のmapはスプライトのIDから画像内の位置への対応
+func MergeSprites(sprites []sprite.Sprite) (img image.Image, rectsMap map[string]image.Rectangle, err error) {
+	imgs := make([]image.Image, len(sprites))
+	for i, s := range sprites {
+		if !s.IsEmpty() {
+			imgs[i] = s.Source
+		}
+	}
+	img, rects, err := merge.Merge(imgs)
+	if err != nil {
+		return
+	}
+	rectsMap = make(map[string]image.Rectangle)
+	for i, rect := range rects {
+		rectsMap[sprites[i].Id()] = rect
+	}
+	return
+}
+
 type WriteSpriteSheetResult struct {
 	RectsMap map[string]image.Rectangle
-	Err      error
+	// 書き出した画像のサイズ
+	Size image.Point
+	Err  error
 }
 
 func WriteSpriteSheet(ch chan WriteSpriteSheetResult, sprites []sprite.Sprite, path string) {
@@ -29,13 +57,7 @@ func WriteSpriteSheet(ch chan WriteSpriteSheetResult, sprites []sprite.Sprite, p
 	defer func() {
 		ch <- result
 	}()
-	imgs := make([]image.Image, len(sprites))
-	for i, s := range sprites {
-		if !s.IsEmpty() {
-			imgs[i] = s.Image
-		}
-	}
-	img, rects, err := merge.Merge(imgs)
+	img, rectsMap, err := MergeSprites(sprites)
 	if err != nil {
 		result.Err = err
 		return
@@ -45,8 +67,83 @@ func WriteSpriteSheet(ch chan WriteSpriteSheetResult, sprites []sprite.Sprite, p
 		result.Err = err
 		return
 	}
-	result.RectsMap = make(map[string]image.Rectangle)
-	for i, rect := range rects {
-		result.RectsMap[sprites[i].Id()] = rect
+	result.RectsMap = rectsMap
+	result.Size = img.Bounds().Size()
+}
+
+// 他の形式への書き出しで使うスプライトシートをpathに書き出す
+// スプライトがなければ何も書き出さず、空のmapを返す
+func writeExportSheet(sprites []sprite.Sprite, path string) (rectsMap map[string]image.Rectangle, size image.Point, err error) {
+	if len(sprites) == 0 {
+		rectsMap = map[string]image.Rectangle{}
+		return
+	}
+	img, rectsMap, err := MergeSprites(sprites)
+	if err != nil {
+		return
+	}
+	err = writeExportPng(img, path)
+	if err != nil {
+		return
+	}
+	size = img.Bounds().Size()
+	return
+}
+
+// 他の形式への書き出しで使う画像をpathに書き出す
+// プロジェクトのスプライトシートを上書きしてしまう場合は書き出さない
+func writeExportPng(img image.Image, path string) error {
+	if isProjectSheet(path) {
+		return fmt.Errorf("%s is the sprite sheet of a project", path)
+	}
+	return WritePng(img, path)
+}
+
+// pathが同じディレクトリにあるプロジェクトのスプライトシートかどうか
+func isProjectSheet(path string) bool {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	bytes, err := os.ReadFile(filepath.Join(filepath.Dir(path), name+".json"))
+	if err != nil {
+		return false
+	}
+	var animationP animation.AnimationP
+	if json.Unmarshal(bytes, &animationP) != nil {
+		return false
+	}
+	return animationP.Animation != nil && animationP.Name == name
+}
+
+// "<name>.png"と"<name>.json"をdirに書き出す
+// libraryはExplorerに並んでいるスプライトで、パーツで使われていないものも書き出す
+func WriteProject(ch chan error, dir, name string, a *animation.Animation, library []sprite.Sprite) {
+	var err error
+	defer func() {
+		ch <- err
+	}()
+	spriteSheet := map[string]image.Rectangle{}
+	sprites, entries := animation.NewLibrary(library, a)
+	// スプライトシートの出力
+	if len(sprites) != 0 {
+		var img image.Image
+		img, spriteSheet, err = MergeSprites(sprites)
+		if err != nil {
+			return
+		}
+		err = WritePng(img, filepath.Join(dir, name+".png"))
+		if err != nil {
+			return
+		}
+	}
+	// AnimationのJSON出力
+	bytes, err := animation.MarshalJson(animation.AnimationP{
+		FormatVersion: constant.FormatVersion,
+		Name:          name,
+		Animation:     a,
+		SpriteSheet:   spriteSheet,
+		Library:       entries,
+	})
+	if err != nil {
+		return
 	}
+	err = os.WriteFile(filepath.Join(dir, name+".json"), bytes, 0644)
 }
@@ -2,18 +2,26 @@ package main
 
 import (
 	"log"
+	"os"
 
+	"github.com/aethiopicuschan/odori/command"
 	"github.com/aethiopicuschan/odori/constant"
 	"github.com/aethiopicuschan/odori/game"
 	"github.com/hajimehoshi/ebiten/v2"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		// Linuxではここに来る前にebitenがディスプレイを要求するので、ディスプレイのない環境ではodori-exportを使う
+		os.Exit(command.Run(os.Args[1:]))
+	}
 	ebiten.SetWindowSize(constant.DefaultScreenWidth, constant.DefaultScreenHeight)
 	ebiten.SetWindowSizeLimits(constant.MinimumScreenWidth, constant.MinimumScreenHeight, -1, -1)
 	ebiten.SetWindowTitle(constant.WindowTitle)
 	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
 	ebiten.SetRunnableOnUnfocused(true)
+	// 保存していない変更があるときに確認するため、閉じる処理はGameで行う
+	ebiten.SetWindowClosingHandled(true)
 	game := game.NewGame()
 	if err := ebiten.RunGame(game); err != nil {
 		log.Fatal(err)
@@ -0,0 +1,209 @@
+// Odoriで書き出したアニメーションをEbitengine製のゲームで再生するためのパッケージ
+package playback
+
+import (
+	"io/fs"
+	"time"
+
+	"github.com/aethiopicuschan/odori/animation"
+	"github.com/aethiopicuschan/odori/sprite"
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+type Mode int
+
+const (
+	// 最後まで再生したら最初に戻る
+	Loop Mode = iota
+	// 最後まで再生したら最後のパーツで止まる
+	Once
+)
+
+type Player struct {
+	animation  *animation.Animation
+	indexes    []int
+	maxTick    int
+	tick       int
+	tps        int
+	elapsed    time.Duration
+	mode       Mode
+	finished   bool
+	onComplete func()
+	canvas     *ebiten.Image
+	// スプライトのIDごとの描画用の画像
+	images map[string]*ebiten.Image
+}
+
+func NewPlayer(a *animation.Animation) *Player {
+	p := &Player{
+		animation: a,
+		tps:       ebiten.TPS(),
+		mode:      Loop,
+		images:    map[string]*ebiten.Image{},
+	}
+	p.indexes, p.maxTick = a.Indexes()
+	return p
+}
+
+// Exportで書き出したJSONとスプライトシートを読み込む
+// nameはfsys内のJSONのパスで、スプライトシートは同じディレクトリにある想定
+func Load(fsys fs.FS, name string) (p *Player, err error) {
+	animationP, _, err := animation.ReadProject(fsys, name)
+	if err != nil {
+		return
+	}
+	p = NewPlayer(animationP.Animation)
+	return
+}
+
+func (p *Player) Animation() *animation.Animation {
+	return p.animation
+}
+
+func (p *Player) SetMode(mode Mode) {
+	p.mode = mode
+}
+
+// AdvanceByで時間をTickに変換する際のTPS
+// デフォルトはebiten.TPS()
+func (p *Player) SetTPS(tps int) {
+	p.tps = tps
+}
+
+// 最後まで再生した時に呼ばれる
+// Loopの場合は最初に戻るたびに呼ばれる
+func (p *Player) OnComplete(f func()) {
+	p.onComplete = f
+}
+
+// 1Tick進める
+// ebiten.GameのUpdateから毎回呼ぶ想定
+func (p *Player) Update() {
+	p.Advance(1)
+}
+
+// 指定したTick数だけ進める
+func (p *Player) Advance(ticks int) {
+	for i := 0; i < ticks; i++ {
+		if p.finished || p.maxTick == 0 {
+			return
+		}
+		p.tick++
+		if p.tick < p.maxTick {
+			continue
+		}
+		switch p.mode {
+		case Loop:
+			p.tick = 0
+		case Once:
+			p.tick = p.maxTick - 1
+			p.finished = true
+		}
+		if p.onComplete != nil {
+			p.onComplete()
+		}
+	}
+}
+
+// 経過時間だけ進める
+// Tickに満たない端数は次回に持ち越す
+func (p *Player) AdvanceBy(d time.Duration) {
+	if p.tps <= 0 {
+		return
+	}
+	p.elapsed += d
+	perTick := time.Second / time.Duration(p.tps)
+	ticks := int(p.elapsed / perTick)
+	p.elapsed -= time.Duration(ticks) * perTick
+	p.Advance(ticks)
+}
+
+// 最初から再生し直す
+func (p *Player) Reset() {
+	p.tick = 0
+	p.elapsed = 0
+	p.finished = false
+	p.indexes, p.maxTick = p.animation.Indexes()
+}
+
+func (p *Player) Tick() int {
+	return p.tick
+}
+
+func (p *Player) MaxTick() int {
+	return p.maxTick
+}
+
+func (p *Player) IsFinished() bool {
+	return p.finished
+}
+
+// 現在のパーツの添字
+// パーツがない場合は-1
+func (p *Player) PartIndex() int {
+	return animation.PartIndexAt(p.indexes, p.tick)
+}
+
+// 現在のパーツ
+// パーツがない場合はnil
+func (p *Player) Part() *animation.Part {
+	index := p.PartIndex()
+	if index < 0 {
+		return nil
+	}
+	return p.animation.Parts[index]
+}
+
+// 現在のパーツをdstに描画する
+// エディタのプレビューと同じくアニメーションのサイズのキャンバスに描画してから、geoMを適用する
+func (p *Player) Draw(dst *ebiten.Image, geoM ebiten.GeoM) {
+	part := p.Part()
+	if part == nil {
+		return
+	}
+	img := p.image(part.Sprite)
+	if img == nil {
+		return
+	}
+	w, h := p.animation.Width, p.animation.Height
+	if p.canvas == nil || p.canvas.Bounds().Dx() != w || p.canvas.Bounds().Dy() != h {
+		if p.canvas != nil {
+			p.canvas.Dispose()
+		}
+		p.canvas = ebiten.NewImage(w, h)
+	}
+	p.canvas.Clear()
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM = GeoM(p.animation, part)
+	p.canvas.DrawImage(img, op)
+	canvasOp := &ebiten.DrawImageOptions{}
+	canvasOp.GeoM = geoM
+	dst.DrawImage(p.canvas, canvasOp)
+}
+
+// 描画用の画像は実際に描画されるまで作らない
+// 画素データがない場合はnilを返す
+func (p *Player) image(s sprite.Sprite) *ebiten.Image {
+	if s.IsEmpty() || s.Source == nil {
+		return nil
+	}
+	img, ok := p.images[s.Id()]
+	if !ok {
+		img = ebiten.NewImageFromImage(s.Source)
+		p.images[s.Id()] = img
+	}
+	return img
+}
+
+// パーツをアニメーションのキャンバスに配置するためのGeoM
+// エディタのプレビューやエクスポートと同じくanimation.Transformを使う
+func GeoM(a *animation.Animation, part *animation.Part) (geoM ebiten.GeoM) {
+	m := a.Transform(part)
+	geoM.SetElement(0, 0, m[0])
+	geoM.SetElement(0, 1, m[1])
+	geoM.SetElement(0, 2, m[2])
+	geoM.SetElement(1, 0, m[3])
+	geoM.SetElement(1, 1, m[4])
+	geoM.SetElement(1, 2, m[5])
+	return
+}
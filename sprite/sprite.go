@@ -3,30 +3,45 @@ package sprite
 import (
 	"encoding/json"
 	"image"
+	"image/draw"
+	"slices"
 
 	"github.com/google/uuid"
-	"github.com/hajimehoshi/ebiten/v2"
 )
 
+// 描画用のebiten.Imageは持たず、描画する側でIDごとに作る
+// ウィンドウのない環境でもエクスポートできるように、このパッケージはebitenに依存しない
 type Sprite struct {
-	Image *ebiten.Image
-	id    string
+	// 元の画素データ
+	Source image.Image
+	id     string
+	// アトラスのフレーム名など
+	name string
 }
 
 func NewSprite(img image.Image) (sprite Sprite) {
-	return Sprite{
-		Image: ebiten.NewImageFromImage(img),
-		id:    uuid.NewString(),
-	}
+	return NewSpriteWithId(img, uuid.NewString())
 }
 
 func NewSpriteWithId(img image.Image, id string) (sprite Sprite) {
+	// SubImageなどで原点がずれていると扱いにくいので、左上が(0, 0)になるようにコピーする
+	if img.Bounds().Min != (image.Point{}) {
+		rgba := image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+		draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+		img = rgba
+	}
 	return Sprite{
-		Image: ebiten.NewImageFromImage(img),
-		id:    id,
+		Source: img,
+		id:     id,
 	}
 }
 
+func NewSpriteWithName(img image.Image, name string) (sprite Sprite) {
+	sprite = NewSprite(img)
+	sprite.name = name
+	return
+}
+
 type SubImager interface {
 	SubImage(r image.Rectangle) image.Image
 }
@@ -38,32 +53,58 @@ func NewSpriteFromRects(img image.Image, rects []image.Rectangle) (sprites []Spr
 	return
 }
 
+// マップの順序は毎回変わるので、IDの順に並べる
 func NewSpritesFromRectMap(img image.Image, rectMap map[string]image.Rectangle) (sprites []Sprite) {
-	for id, rect := range rectMap {
-		sprites = append(sprites, NewSpriteWithId(img.(SubImager).SubImage(rect), id))
+	ids := make([]string, 0, len(rectMap))
+	for id := range rectMap {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		sprites = append(sprites, NewSpriteWithId(img.(SubImager).SubImage(rectMap[id]), id))
 	}
 	return
 }
 
 func NewEmptySprite() (sprite Sprite) {
 	return Sprite{
-		Image: nil,
-		id:    "",
+		Source: nil,
+		id:     "",
 	}
 }
 
 func (s *Sprite) IsEmpty() bool {
-	return s.Image == nil
+	return s.id == ""
+}
+
+// 画素データがない場合は空の矩形を返す
+func (s *Sprite) Bounds() image.Rectangle {
+	if s.Source == nil {
+		return image.Rectangle{}
+	}
+	return s.Source.Bounds()
 }
 
 func (s *Sprite) Id() string {
 	return s.id
 }
 
+// 名前がない場合は空文字列を返す
+func (s *Sprite) Name() string {
+	return s.name
+}
+
+// 名前を付けたコピーを返す
+func (s Sprite) WithName(name string) Sprite {
+	s.name = name
+	return s
+}
+
 // スプライトの永続化モデル
 type SpriteP struct {
 	Id      string `json:"id"`
 	IsEmpty bool   `json:"isEmpty"`
+	Name    string `json:"name,omitempty"`
 }
 
 func (s *Sprite) MarshalJSON() ([]byte, error) {
@@ -74,6 +115,7 @@ func (s *Sprite) MarshalJSON() ([]byte, error) {
 	spriteP := SpriteP{
 		Id:      id,
 		IsEmpty: s.IsEmpty(),
+		Name:    s.name,
 	}
 	return json.Marshal(spriteP)
 }
@@ -84,12 +126,13 @@ func (s *Sprite) UnmarshalJSON(bytes []byte) (err error) {
 	if err != nil {
 		return
 	}
-	if spriteP.IsEmpty {
-		s.Image = nil
-	} else {
-		// 仮の画像を入れておく
-		s.Image = ebiten.NewImage(1, 1)
+	// 画素データはスプライトシートを読み込んだ後に差し替える
+	s.Source = nil
+	s.id = ""
+	s.name = ""
+	if !spriteP.IsEmpty {
 		s.id = spriteP.Id
+		s.name = spriteP.Name
 	}
 	return
 }
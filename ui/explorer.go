@@ -8,6 +8,10 @@ import (
 	"github.com/aethiopicuschan/odori/sprite"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
 )
 
 type scrollBar struct {
@@ -33,10 +37,16 @@ type Explorer struct {
 	offsetX          int
 	offsetY          int
 	size             int
+	font             font.Face
 }
 
 func NewExplorer(onPick func(s sprite.Sprite)) *Explorer {
 	_, h := ebiten.WindowSize()
+	tt, _ := opentype.Parse(goregular.TTF)
+	font, _ := opentype.NewFace(tt, &opentype.FaceOptions{
+		Size: 10,
+		DPI:  72,
+	})
 	return &Explorer{
 		onPick: onPick,
 		sprites: []sprite.Sprite{
@@ -50,6 +60,7 @@ func NewExplorer(onPick func(s sprite.Sprite)) *Explorer {
 		offsetX:          10,
 		offsetY:          10,
 		size:             100,
+		font:             font,
 		scrollBar: scrollBar{
 			show:     false,
 			height:   0,
@@ -64,6 +75,12 @@ func (e *Explorer) AppendSprite(sprite sprite.Sprite) {
 	e.sprites = append(e.sprites, sprite)
 }
 
+// 読み込まれているスプライト
+// 先頭の空のスプライトは含まない
+func (e *Explorer) Sprites() []sprite.Sprite {
+	return append([]sprite.Sprite{}, e.sprites[1:]...)
+}
+
 func (e *Explorer) Update() error {
 	if e.doubleClickCount >= 0 {
 		e.doubleClickCount++
@@ -255,8 +272,7 @@ func (e *Explorer) Draw(screen *ebiten.Image) {
 			frameOp.GeoM.Translate(0, e.scrollOffset)
 		}
 		// Draw Sprite.
-		if !sprite.IsEmpty() {
-			img := sprite.Image
+		if img := spriteImage(sprite); img != nil {
 			width := img.Bounds().Dx()
 			height := img.Bounds().Dy()
 			scale := 1.0
@@ -280,6 +296,14 @@ func (e *Explorer) Draw(screen *ebiten.Image) {
 			spriteOp.GeoM.Translate(float64(e.size/2), float64(e.size/2))
 			frame.DrawImage(img, spriteOp)
 		}
+		// 名前があれば下端に表示する
+		if name := sprite.Name(); name != "" {
+			label := []rune(name)
+			for len(label) > 1 && text.BoundString(e.font, string(label)).Dx() > e.size-4 {
+				label = label[:len(label)-1]
+			}
+			text.Draw(frame, string(label), e.font, 2, e.size-3, color.Black)
+		}
 		if i == e.cursolOn {
 			frameLine := ebiten.NewImage(e.size+2, e.size+2)
 			frameLine.Fill(color.RGBA{R: 0, G: 0, B: 255, A: 255})
@@ -0,0 +1,24 @@
+package ui
+
+import (
+	"github.com/aethiopicuschan/odori/sprite"
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// スプライトのIDごとの描画用の画像
+// ExplorerとPlayerで共有し、実際に描画されるまで作らない
+var spriteImages = map[string]*ebiten.Image{}
+
+// 画素データがない場合はnilを返す
+// Drawからだけ呼ぶ想定
+func spriteImage(s sprite.Sprite) *ebiten.Image {
+	if s.IsEmpty() || s.Source == nil {
+		return nil
+	}
+	img, ok := spriteImages[s.Id()]
+	if !ok {
+		img = ebiten.NewImageFromImage(s.Source)
+		spriteImages[s.Id()] = img
+	}
+	return img
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/aethiopicuschan/odori/animation"
 	"github.com/aethiopicuschan/odori/constant"
 	"github.com/aethiopicuschan/odori/io"
+	"github.com/aethiopicuschan/odori/playback"
 	"github.com/aethiopicuschan/odori/sprite"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
@@ -39,6 +40,8 @@ type Player struct {
 	noticer     *Noticer
 	name        string
 	funcMap     map[string]func()
+	// 最後に保存してから変更があったか
+	dirty bool
 }
 
 func NewPlayer(name string, noticer *Noticer, funcMap map[string]func()) *Player {
@@ -152,6 +155,7 @@ func NewPlayer(name string, noticer *Noticer, funcMap map[string]func()) *Player
 					return
 				}
 				part.Scale = scale
+				p.dirty = true
 			}()
 		}),
 		NewLink(0, 0, "DiffX", func() {
@@ -178,6 +182,7 @@ func NewPlayer(name string, noticer *Noticer, funcMap map[string]func()) *Player
 					return
 				}
 				part.DiffX = diffX
+				p.dirty = true
 			}()
 		}),
 		NewLink(0, 0, "DiffY", func() {
@@ -204,6 +209,7 @@ func NewPlayer(name string, noticer *Noticer, funcMap map[string]func()) *Player
 					return
 				}
 				part.DiffY = diffY
+				p.dirty = true
 			}()
 		}),
 		NewLink(0, 0, "Reverse", func() {
@@ -213,6 +219,7 @@ func NewPlayer(name string, noticer *Noticer, funcMap map[string]func()) *Player
 			}
 			part := p.animation.Parts[p.currentPart]
 			part.Reverse = !part.Reverse
+			p.dirty = true
 		}),
 		NewLink(0, 0, "Len", func() {
 			p.playing = false
@@ -242,6 +249,7 @@ func NewPlayer(name string, noticer *Noticer, funcMap map[string]func()) *Player
 					return
 				}
 				part.Length = length
+				p.dirty = true
 				// lengthが変わった関係で諸々のパラメータをリセットする必要がある
 				p.resetIndexes()
 			}()
@@ -257,8 +265,8 @@ func NewPlayer(name string, noticer *Noticer, funcMap map[string]func()) *Player
 				return
 			}
 			scale := 1.0
-			width := part.Sprite.Image.Bounds().Dx()
-			height := part.Sprite.Image.Bounds().Dy()
+			width := part.Sprite.Bounds().Dx()
+			height := part.Sprite.Bounds().Dy()
 			if width < p.animation.Width && height < p.animation.Height {
 				if width > height {
 					scale = float64(p.animation.Width / width)
@@ -274,6 +282,7 @@ func NewPlayer(name string, noticer *Noticer, funcMap map[string]func()) *Player
 				}
 			}
 			part.Scale = scale
+			p.dirty = true
 		}),
 		NewLink(0, 0, "Reset", func() {
 			p.playing = false
@@ -294,6 +303,7 @@ func NewPlayer(name string, noticer *Noticer, funcMap map[string]func()) *Player
 				part.DiffY = 0
 				part.Reverse = false
 				part.Length = ebiten.TPS()
+				p.dirty = true
 				p.resetIndexes()
 			}()
 		}),
@@ -320,6 +330,7 @@ func NewPlayer(name string, noticer *Noticer, funcMap map[string]func()) *Player
 						p.currentPart = 0
 					}
 				}
+				p.dirty = true
 				p.resetIndexes()
 			}()
 		}),
@@ -331,15 +342,10 @@ func NewPlayer(name string, noticer *Noticer, funcMap map[string]func()) *Player
 }
 
 func (p *Player) resetIndexes() {
-	p.maxTick = 0
+	p.indexes, p.maxTick = p.animation.Indexes()
 	p.currentTick = 0
-	p.indexes = []int{}
-	for i, part := range p.animation.Parts {
-		if i == p.currentPart {
-			p.currentTick = p.maxTick
-		}
-		p.indexes = append(p.indexes, p.maxTick)
-		p.maxTick += part.Length
+	if p.currentPart >= 0 && p.currentPart < len(p.indexes) {
+		p.currentTick = p.indexes[p.currentPart]
 	}
 }
 
@@ -353,6 +359,7 @@ func (p *Player) Append(sprite sprite.Sprite) {
 	p.currentTick = p.maxTick
 	p.currentPart = len(p.animation.Parts) - 1
 	p.maxTick += length
+	p.dirty = true
 }
 
 func (p *Player) Update() error {
@@ -408,14 +415,14 @@ func (p *Player) Update() error {
 				if part.Sprite.IsEmpty() {
 					l.SetLabel("Size: -")
 				} else {
-					l.SetLabel(fmt.Sprintf("Size: %dx%d", part.Sprite.Image.Bounds().Dx(), part.Sprite.Image.Bounds().Dy()))
+					l.SetLabel(fmt.Sprintf("Size: %dx%d", part.Sprite.Bounds().Dx(), part.Sprite.Bounds().Dy()))
 				}
 			}
 			if l.id == "Scale" {
 				if part.Sprite.IsEmpty() {
 					l.SetLabel("Scale: -")
 				} else {
-					l.SetLabel(fmt.Sprintf("Scale: %0.2f (=%0.2fx%0.2f)", part.Scale, float64(part.Sprite.Image.Bounds().Dx())*part.Scale, float64(part.Sprite.Image.Bounds().Dy())*part.Scale))
+					l.SetLabel(fmt.Sprintf("Scale: %0.2f (=%0.2fx%0.2f)", part.Scale, float64(part.Sprite.Bounds().Dx())*part.Scale, float64(part.Sprite.Bounds().Dy())*part.Scale))
 				}
 			}
 			if l.id == "DiffX" {
@@ -507,13 +514,7 @@ func (p *Player) Update() error {
 		if p.currentTick >= p.maxTick {
 			p.currentTick = p.maxTick - 1
 		}
-		for i, index := range p.indexes {
-			if p.currentTick >= index {
-				p.currentPart = i
-			} else {
-				break
-			}
-		}
+		p.currentPart = animation.PartIndexAt(p.indexes, p.currentTick)
 	}
 
 	return nil
@@ -581,21 +582,9 @@ func (p *Player) Draw(screen *ebiten.Image) {
 	// Draw part.
 	if p.currentPart >= 0 {
 		part := p.animation.Parts[p.currentPart]
-		scale := part.Scale
-		diffX := part.DiffX
-		diffY := part.DiffY
-		reverse := part.Reverse
-		if !part.Sprite.IsEmpty() {
-			img := part.Sprite.Image
+		if img := spriteImage(part.Sprite); img != nil {
 			op := &ebiten.DrawImageOptions{}
-			if reverse {
-				op.GeoM.Scale(-1, 1)
-				op.GeoM.Translate(float64(img.Bounds().Dx()), 0)
-				diffX *= -1
-			}
-			op.GeoM.Scale(scale, scale)
-			op.GeoM.Translate(-((float64(img.Bounds().Dx()-diffX))*scale)/2, -((float64(img.Bounds().Dy()-diffY))*scale)/2)
-			op.GeoM.Translate(float64(p.animation.Width/2), float64(p.animation.Height/2))
+			op.GeoM = playback.GeoM(p.animation, part)
 			frame.DrawImage(img, op)
 		}
 	}
@@ -671,11 +660,35 @@ func (p *Player) Stop() {
 	p.playing = false
 }
 
+// 読み込んだ直後は保存済みとして扱う
 func (p *Player) Import(a *animation.Animation) {
 	p.animation = a
 	p.resetIndexes()
+	p.dirty = false
 }
 
 func (p *Player) Rename(name string) {
 	p.name = name
+	p.dirty = true
+}
+
+func (p *Player) Resize(width, height int) {
+	p.animation.Width = width
+	p.animation.Height = height
+	p.dirty = true
+}
+
+// 最後に保存してから変更があったか
+func (p *Player) IsDirty() bool {
+	return p.dirty
+}
+
+// プロジェクトの外で変更があったときに呼ぶ
+func (p *Player) MarkDirty() {
+	p.dirty = true
+}
+
+// 保存したときに呼ぶ
+func (p *Player) MarkSaved() {
+	p.dirty = false
 }